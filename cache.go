@@ -0,0 +1,115 @@
+package libudev
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// CachedScanner caches the devices returned by a scanner, so that they
+// can be shared by frequent callers without rescanning sysfs each time.
+// The cache is refreshed on the first Scan after Invalidate is called or
+// the TTL set with WithCacheTTL elapses.
+//
+// It is safe for concurrent use, and concurrent Scans that find the
+// cache stale share a single rescan. The returned devices are shared by
+// all callers, so they must be treated as read-only.
+type CachedScanner struct {
+	scanner *scanner
+	ttl     time.Duration
+
+	// scan and now are replaced in tests.
+	scan func() ([]*types.Device, error)
+	now  func() time.Time
+
+	mu        sync.Mutex
+	devices   []*types.Device
+	scannedAt time.Time
+	valid     bool
+	// gen is incremented by Invalidate, so that scans started before
+	// it are not cached.
+	gen  uint64
+	call *cacheCall
+}
+
+// cacheCall is a rescan in flight, shared by concurrent Scans of the
+// same generation.
+type cacheCall struct {
+	gen     uint64
+	done    chan struct{}
+	devices []*types.Device
+	err     error
+}
+
+// NewCachedScanner creates a new scanner with opts, whose results are
+// cached for the TTL set with WithCacheTTL.
+func NewCachedScanner(opts ...Option) (*CachedScanner, error) {
+	s, err := NewScanner(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CachedScanner{
+		scanner: s,
+		ttl:     s.opts.cacheTTL,
+		scan:    s.ScanDevices,
+		now:     time.Now,
+	}, nil
+}
+
+// Scan returns the cached devices, scanning them with ScanDevices when
+// the cache is empty, invalidated or expired. Errors are not cached.
+func (c *CachedScanner) Scan() ([]*types.Device, error) {
+	c.mu.Lock()
+	if c.valid && (c.ttl <= 0 || c.now().Sub(c.scannedAt) < c.ttl) {
+		devices := c.devices
+		c.mu.Unlock()
+		return devices, nil
+	}
+
+	// Scans started after Invalidate do not join the rescan in flight,
+	// which may have read the devices before the change.
+	if call := c.call; call != nil && call.gen == c.gen {
+		c.mu.Unlock()
+		<-call.done
+		return call.devices, call.err
+	}
+
+	call := &cacheCall{gen: c.gen, done: make(chan struct{})}
+	c.call = call
+	c.mu.Unlock()
+
+	call.devices, call.err = c.scan()
+
+	c.mu.Lock()
+	if c.call == call {
+		c.call = nil
+	}
+	if call.err == nil && call.gen == c.gen {
+		c.devices = call.devices
+		c.scannedAt = c.now()
+		c.valid = true
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.devices, call.err
+}
+
+// Invalidate drops the cached devices, so that the next Scan rescans
+// them. Scans in flight are not cached, nor joined by later Scans.
+func (c *CachedScanner) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.devices = nil
+	c.valid = false
+	c.gen++
+}
+
+// Close releases the underlying scanner. The CachedScanner must not be
+// used after Close.
+func (c *CachedScanner) Close() error {
+	return c.scanner.Close()
+}
@@ -0,0 +1,221 @@
+package libudev
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// newCachedDemoScanner returns a CachedScanner over the demo tree, with a
+// fake clock and a count of the scans done.
+func newCachedDemoScanner(t *testing.T, ttl time.Duration) (*CachedScanner, *time.Time, *atomic.Int32) {
+	t.Helper()
+
+	s := newDemoScanner(t)
+	c, err := NewCachedScanner(
+		WithDevicesRoot(s.opts.devicesRoot),
+		WithUDevDataRoot(s.opts.udevDataRoot),
+		WithClassRoot(s.opts.classRoot),
+		WithCacheTTL(ttl),
+	)
+	if err != nil {
+		t.Fatal("failed to create cached scanner", err)
+	}
+
+	now := time.Unix(0, 0)
+	var scans atomic.Int32
+	scan := c.scan
+	c.scan = func() ([]*types.Device, error) {
+		scans.Add(1)
+		return scan()
+	}
+	c.now = func() time.Time { return now }
+
+	return c, &now, &scans
+}
+
+func TestCachedScanner(t *testing.T) {
+	c, now, scans := newCachedDemoScanner(t, time.Second)
+
+	first, err := c.Scan()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if len(first) != 11 {
+		t.Fatalf("wanted 11 devices got %d", len(first))
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	cached, err := c.Scan()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if scans.Load() != 1 || cached[0] != first[0] {
+		t.Fatalf("want cached devices, got %d scans", scans.Load())
+	}
+
+	*now = now.Add(time.Second)
+	expired, err := c.Scan()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if scans.Load() != 2 || expired[0] == first[0] {
+		t.Fatalf("want a rescan after the TTL, got %d scans", scans.Load())
+	}
+
+	c.Invalidate()
+	if _, err := c.Scan(); err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if scans.Load() != 3 {
+		t.Fatalf("want a rescan after Invalidate, got %d scans", scans.Load())
+	}
+}
+
+func TestCachedScannerNoTTL(t *testing.T) {
+	c, now, scans := newCachedDemoScanner(t, 0)
+
+	for range 3 {
+		if _, err := c.Scan(); err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+		*now = now.Add(time.Hour)
+	}
+
+	if scans.Load() != 1 {
+		t.Errorf("want the cache to never expire, got %d scans", scans.Load())
+	}
+}
+
+func TestCachedScannerErrors(t *testing.T) {
+	c, _, scans := newCachedDemoScanner(t, time.Minute)
+
+	errScan := errors.New("scan failed")
+	scan := c.scan
+	c.scan = func() ([]*types.Device, error) {
+		if scans.Load() == 0 {
+			scans.Add(1)
+			return nil, errScan
+		}
+		return scan()
+	}
+
+	if _, err := c.Scan(); !errors.Is(err, errScan) {
+		t.Fatalf("want scan error got %v", err)
+	}
+
+	devices, err := c.Scan()
+	if err != nil || len(devices) != 11 {
+		t.Fatalf("want errors not cached, got %d devices and %v", len(devices), err)
+	}
+}
+
+func TestCachedScannerConcurrent(t *testing.T) {
+	c, _, scans := newCachedDemoScanner(t, time.Minute)
+
+	// Hold the first scan until every caller is waiting on it.
+	release := make(chan struct{})
+	scan := c.scan
+	c.scan = func() ([]*types.Device, error) {
+		<-release
+		return scan()
+	}
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([][]*types.Device, callers)
+	for i := range callers {
+		wg.Go(func() {
+			devices, err := c.Scan()
+			if err != nil {
+				t.Error("failed to scan the demo tree", err)
+			}
+			results[i] = devices
+		})
+	}
+
+	for {
+		c.mu.Lock()
+		inflight := c.call != nil
+		c.mu.Unlock()
+		if inflight {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if scans.Load() != 1 {
+		t.Errorf("want a single scan got %d", scans.Load())
+	}
+	for i, devices := range results {
+		if len(devices) == 0 || devices[0] != results[0][0] {
+			t.Errorf("caller %d got different devices", i)
+		}
+	}
+}
+
+func TestCachedScannerInvalidateInFlight(t *testing.T) {
+	c, _, _ := newCachedDemoScanner(t, time.Minute)
+
+	// The first scan is held, and returns the devices from before the
+	// change, while later scans return the new ones.
+	oldDevices := []*types.Device{{Devpath: "old"}}
+	newDevices := []*types.Device{{Devpath: "new"}}
+	release := make(chan struct{})
+	var scans atomic.Int32
+	c.scan = func() ([]*types.Device, error) {
+		if scans.Add(1) == 1 {
+			<-release
+			return oldDevices, nil
+		}
+		return newDevices, nil
+	}
+
+	first := make(chan []*types.Device)
+	go func() {
+		devices, _ := c.Scan()
+		first <- devices
+	}()
+
+	for {
+		c.mu.Lock()
+		inflight := c.call != nil
+		c.mu.Unlock()
+		if inflight {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Invalidate()
+
+	devices, err := c.Scan()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+	if len(devices) != 1 || devices[0].Devpath != "new" {
+		t.Errorf("want the devices after Invalidate got %v", devpaths(devices))
+	}
+
+	close(release)
+	if devices := <-first; len(devices) != 1 || devices[0].Devpath != "old" {
+		t.Errorf("want the scan in flight to return its own devices got %v", devpaths(devices))
+	}
+
+	devices, err = c.Scan()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+	if len(devices) != 1 || devices[0].Devpath != "new" {
+		t.Errorf("want the devices after Invalidate cached got %v", devpaths(devices))
+	}
+	if scans.Load() != 2 {
+		t.Errorf("want 2 scans got %d", scans.Load())
+	}
+}
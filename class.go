@@ -0,0 +1,99 @@
+package libudev
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// defaultClassPath is the class dir opened by ScanClass when WithClassRoot
+// is not provided.
+var defaultClassPath = "/sys/class"
+
+// ScanClass returns the devices of a class (e.g. `net` or `tty`), by
+// resolving the symlinks found in its dir of the class root into the
+// devices root. This is much faster than ScanDevices for targeted
+// queries, as the devices tree is not walked.
+//
+// The devices are returned in the order of their class entries, without
+// their Parent and Children links. Entries that cannot be resolved are
+// reported to the error handler and skipped.
+func (s *scanner) ScanClass(subsystem string) ([]*types.Device, error) {
+	if !filepath.IsLocal(subsystem) || strings.Contains(subsystem, "/") {
+		return nil, fmt.Errorf("invalid class %q", subsystem)
+	}
+
+	root, err := s.classRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(root.FS(), subsystem)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*types.Device
+	for _, e := range entries {
+		if e.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		path := filepath.Join(subsystem, e.Name())
+		device, err := s.classDevice(root, path)
+		if err != nil {
+			s.handleError(path, err)
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// classRoot returns the root set with WithClassRoot, opening the default
+// one on first use, so that scanners that never call ScanClass do not
+// depend on it.
+func (s *scanner) classRoot() (*os.Root, error) {
+	s.rootMu.Lock()
+	defer s.rootMu.Unlock()
+
+	if s.opts.classRoot != nil {
+		return s.opts.classRoot, nil
+	}
+
+	r, err := os.OpenRoot(defaultClassPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.opts.classRoot = r
+	s.owned = append(s.owned, r)
+	return r, nil
+}
+
+// classDevice returns the device linked from the class entry at path.
+func (s *scanner) classDevice(root *os.Root, path string) (*types.Device, error) {
+	target, err := root.Readlink(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative targets (e.g. `../../devices/virtual/net/lo`) are resolved
+	// from the sysfs dir, shared by the class and devices roots.
+	if !filepath.IsAbs(target) {
+		rel := filepath.Join("class", filepath.Dir(path), target)
+		devpath, ok := strings.CutPrefix(rel, "devices"+string(filepath.Separator))
+		if !ok {
+			return nil, fmt.Errorf("link target %q is not within the devices root", target)
+		}
+		target = devpath
+	}
+
+	return s.GetDevice(target)
+}
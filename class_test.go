@@ -0,0 +1,60 @@
+package libudev
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScanClass(t *testing.T) {
+	eth0 := "pci0000:00/0000:00:04.0/virtio3/net/eth0"
+	var errs []string
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			eth0 + "/uevent":           "INTERFACE=eth0\nIFINDEX=2\n",
+			eth0 + "/address":          "52:54:00:12:34:56\n",
+			"virtual/net/lo/uevent":    "INTERFACE=lo\nIFINDEX=1\n",
+			"virtual/net/lo/address":   "00:00:00:00:00:00\n",
+			"virtual/tty/tty0/uevent":  "DEVNAME=tty0\n",
+			"virtual/net/gone/address": "00:00:00:00:00:01\n",
+		},
+		links: map[string]string{
+			eth0 + "/subsystem":        "../../../../../../class/net",
+			"virtual/net/lo/subsystem": "../../../../class/net",
+		},
+		class: map[string]string{
+			"net/eth0": "../../devices/" + eth0,
+			"net/lo":   "../../devices/virtual/net/lo",
+			"net/gone": "../../devices/virtual/net/gone",
+			"net/out":  "../../../outside",
+			"tty/tty0": "../../devices/virtual/tty/tty0",
+		},
+	}, WithErrorHandler(func(path string, err error) {
+		errs = append(errs, path)
+	}))
+
+	devices, err := s.ScanClass("net")
+	if err != nil {
+		t.Fatal("failed to scan class", err)
+	}
+
+	if got := devpaths(devices); !slices.Equal(got, []string{eth0, "virtual/net/lo"}) {
+		t.Fatalf("want net devices got %v", got)
+	}
+
+	d := devices[0]
+	if d.Subsystem != "net" || d.Env["INTERFACE"] != "eth0" || d.Attrs["address"] != "52:54:00:12:34:56" {
+		t.Errorf("want eth0 populated got %+v", d)
+	}
+
+	if !slices.Equal(errs, []string{"net/gone", "net/out"}) {
+		t.Errorf("want errors for unresolved entries got %v", errs)
+	}
+
+	if _, err := s.ScanClass("../devices"); err == nil {
+		t.Error("want error for invalid class")
+	}
+
+	if _, err := s.ScanClass("missing"); err == nil {
+		t.Error("want error for missing class")
+	}
+}
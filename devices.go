@@ -0,0 +1,204 @@
+package libudev
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qubesome/libudev/matcher"
+	"github.com/qubesome/libudev/types"
+)
+
+// AllTags returns the sorted set of tags found across all devices.
+func AllTags(devices []*types.Device) []string {
+	var tags []string
+	for _, d := range devices {
+		tags = append(tags, d.Tags...)
+	}
+
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// GroupByDriver groups devices by their Driver. Devices with no bound
+// driver are grouped under the empty string.
+func GroupByDriver(devices []*types.Device) map[string][]*types.Device {
+	groups := map[string][]*types.Device{}
+	for _, d := range devices {
+		groups[d.Driver] = append(groups[d.Driver], d)
+	}
+
+	return groups
+}
+
+// FilterBySubsystem returns a new slice with the devices whose Subsystem
+// equals subsystem. The devices are not copied, so their Parent and
+// Children are kept.
+func FilterBySubsystem(devices []*types.Device, subsystem string) []*types.Device {
+	var filtered []*types.Device
+	for _, d := range devices {
+		if d.Subsystem == subsystem {
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
+
+// FilterByTag returns a new slice with the devices tagged with tag. The
+// devices are not copied, so their Parent and Children are kept.
+func FilterByTag(devices []*types.Device, tag string) []*types.Device {
+	var filtered []*types.Device
+	for _, d := range devices {
+		if slices.Contains(d.Tags, tag) {
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
+
+// MatchAnyOf returns the devices matched by at least one of matchers,
+// in a single pass and in their original order. Devices are returned
+// once per Devpath.
+func MatchAnyOf(devices []*types.Device, matchers ...*matcher.Matcher) []*types.Device {
+	seen := map[string]bool{}
+
+	var matched []*types.Device
+	for _, d := range devices {
+		if seen[d.Devpath] {
+			continue
+		}
+
+		for _, m := range matchers {
+			if m.Match(d) {
+				seen[d.Devpath] = true
+				matched = append(matched, d)
+				break
+			}
+		}
+	}
+
+	return matched
+}
+
+// DeviceByDevnode returns the first device whose node is devnode (e.g.
+// `/dev/input/event2` or `input/event2`), matching its `DEVNAME` or,
+// failing that, its Links. It returns nil when there is no match.
+func DeviceByDevnode(devices []*types.Device, devnode string) *types.Device {
+	name := strings.TrimPrefix(devnode, "/dev/")
+	if name == "" {
+		return nil
+	}
+
+	for _, d := range devices {
+		if strings.TrimPrefix(d.Env["DEVNAME"], "/dev/") == name {
+			return d
+		}
+	}
+
+	for _, d := range devices {
+		if slices.Contains(d.Links, name) {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// DeviceByMajorMinor returns the first device whose `dev` attr holds the
+// given major and minor numbers, or nil when there is no match.
+func DeviceByMajorMinor(devices []*types.Device, major, minor int) *types.Device {
+	for _, d := range devices {
+		ma, ok := d.Major()
+		if !ok || ma != major {
+			continue
+		}
+
+		if mi, _ := d.Minor(); mi == minor {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// DedupeByID collapses the devices that share the same ID, such as the
+// same device reached through more than one directory, keeping their
+// first position. Devices are not merged: the one with the most data,
+// counted as its Env, Attrs, Tags and Links entries, is kept, with ties
+// going to the first one found.
+func DedupeByID(devices []*types.Device) []*types.Device {
+	index := map[string]int{}
+
+	var deduped []*types.Device
+	for _, d := range devices {
+		id := d.ID()
+
+		i, ok := index[id]
+		if !ok {
+			index[id] = len(deduped)
+			deduped = append(deduped, d)
+			continue
+		}
+
+		if deviceData(d) > deviceData(deduped[i]) {
+			deduped[i] = d
+		}
+	}
+
+	return deduped
+}
+
+func deviceData(d *types.Device) int {
+	return len(d.Env) + len(d.Attrs) + len(d.Tags) + len(d.Links)
+}
+
+// SortBySeqnum sorts devices by their Seqnum, which roughly follows the
+// order in which the kernel enumerated them. Devices without a Seqnum
+// sort last, keeping their relative order.
+func SortBySeqnum(devices []*types.Device) {
+	slices.SortStableFunc(devices, func(a, b *types.Device) int {
+		sa, oka := a.Seqnum()
+		sb, okb := b.Seqnum()
+
+		switch {
+		case oka && okb:
+			return cmp.Compare(sa, sb)
+		case oka:
+			return -1
+		case okb:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// SortBySysnum sorts devices by Sysname, comparing their Sysnum
+// numerically rather than lexically, so that `sda2` sorts before `sda10`.
+// Devices with the same Sysname prefix and no Sysnum sort first.
+func SortBySysnum(devices []*types.Device) {
+	slices.SortStableFunc(devices, func(a, b *types.Device) int {
+		pa := strings.TrimSuffix(a.Sysname, a.Sysnum)
+		pb := strings.TrimSuffix(b.Sysname, b.Sysnum)
+
+		return cmp.Or(
+			cmp.Compare(pa, pb),
+			cmp.Compare(sysnum(a), sysnum(b)),
+			cmp.Compare(a.Sysname, b.Sysname),
+		)
+	})
+}
+
+// sysnum returns the numeric value of the device Sysnum, or -1 if it has
+// none.
+func sysnum(d *types.Device) int64 {
+	n, err := strconv.ParseInt(d.Sysnum, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
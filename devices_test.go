@@ -0,0 +1,293 @@
+package libudev
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/qubesome/libudev/matcher"
+	"github.com/qubesome/libudev/types"
+)
+
+func TestAllTags(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	want := []string{"seat", "systemd", "udev-configure-printer"}
+	if got := AllTags(devices); !reflect.DeepEqual(got, want) {
+		t.Errorf("want tags %v got %v", want, got)
+	}
+
+	if got := AllTags(nil); len(got) != 0 {
+		t.Errorf("want no tags got %v", got)
+	}
+}
+
+func TestGroupByDriver(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	groups := GroupByDriver(devices)
+	if len(groups) != 2 {
+		t.Fatalf("wanted 2 groups got %d", len(groups))
+	}
+
+	if len(groups["usb"]) != 6 {
+		t.Errorf("wanted 6 devices bound to usb got %d", len(groups["usb"]))
+	}
+
+	if len(groups[""]) != 5 {
+		t.Errorf("wanted 5 unbound devices got %d", len(groups[""]))
+	}
+
+	for driver, group := range groups {
+		for _, d := range group {
+			if d.Driver != driver {
+				t.Errorf("want %q driver %q got %q", d.Devpath, driver, d.Driver)
+			}
+		}
+	}
+}
+
+func TestFilterBySubsystem(t *testing.T) {
+	devices := []*types.Device{
+		{Devpath: "usb1", Subsystem: "usb"},
+		{Devpath: "event2", Subsystem: "input"},
+		{Devpath: "usb1/1-1", Subsystem: "usb"},
+		{Devpath: "platform"},
+	}
+	devices[2].Parent = devices[0]
+
+	got := FilterBySubsystem(devices, "usb")
+	if len(got) != 2 || got[0] != devices[0] || got[1] != devices[2] {
+		t.Fatalf("want the usb devices got %v", got)
+	}
+	if got[1].Parent != devices[0] {
+		t.Error("want parent links kept")
+	}
+
+	if got := FilterBySubsystem(devices, "drm"); len(got) != 0 {
+		t.Errorf("want no devices got %v", got)
+	}
+
+	if len(devices) != 4 || devices[1].Subsystem != "input" {
+		t.Error("original slice modified")
+	}
+}
+
+func TestFilterByTag(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	got := FilterByTag(devices, "seat")
+	if len(got) != 4 {
+		t.Fatalf("wanted 4 devices tagged seat got %d", len(got))
+	}
+	for _, d := range got {
+		if !slices.Contains(d.Tags, "seat") {
+			t.Errorf("device %q is not tagged seat", d.Devpath)
+		}
+	}
+
+	if got := FilterByTag(devices, "missing"); len(got) != 0 {
+		t.Errorf("want no devices got %d", len(got))
+	}
+}
+
+func TestMatchAnyOf(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	seat := matcher.NewMatcher()
+	seat.AddRule(matcher.NewRuleTag("seat"))
+
+	// Overlaps with seat on usb2 and 2-1.
+	bus2 := matcher.NewMatcher()
+	bus2.AddRule(matcher.NewRuleEnv("DEVNAME", "^bus/usb/002/"))
+
+	want := []string{
+		"pci0000:00/0000:00:1a.0/usb1",
+		"pci0000:00/0000:00:1a.0/usb1/1-1",
+		"pci0000:00/0000:00:1d.0/usb2",
+		"pci0000:00/0000:00:1d.0/usb2/2-1",
+		"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2",
+		"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4",
+	}
+	if got := devpaths(MatchAnyOf(devices, seat, bus2)); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+
+	if got := MatchAnyOf(append(devices, devices...), seat, bus2); len(got) != len(want) {
+		t.Errorf("want devices deduplicated, got %d", len(got))
+	}
+
+	if got := MatchAnyOf(devices); len(got) != 0 {
+		t.Errorf("want no devices without matchers got %d", len(got))
+	}
+}
+
+func TestDeviceByDevnode(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	event2 := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2"
+	mouse0 := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/mouse0"
+	tests := map[string]string{
+		"/dev/input/event2": event2,
+		"input/event2":      event2,
+		"/dev/input/by-id/usb-Logitech_USB_Optical_Mouse-mouse": mouse0,
+		"/dev/input/event9": "",
+		"":                  "",
+	}
+
+	for devnode, want := range tests {
+		d := DeviceByDevnode(devices, devnode)
+		if want == "" {
+			if d != nil {
+				t.Errorf("%q: want no device got %q", devnode, d.Devpath)
+			}
+			continue
+		}
+
+		if d == nil || d.Devpath != want {
+			t.Errorf("%q: want %q got %v", devnode, want, d)
+		}
+	}
+}
+
+func TestDeviceByMajorMinor(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	d := DeviceByMajorMinor(devices, 189, 133)
+	if d == nil || d.Devpath != "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4" {
+		t.Errorf("want the printer got %v", d)
+	}
+
+	if d := DeviceByMajorMinor(devices, 189, 999); d != nil {
+		t.Errorf("want no device got %q", d.Devpath)
+	}
+}
+
+func TestDedupeByID(t *testing.T) {
+	// The same printer, reachable through a bind mount of its bus.
+	printer := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4"
+	f := fixture{
+		devices: map[string]string{
+			printer + "/uevent":         "DEVTYPE=usb_device\nDRIVER=usb\n",
+			printer + "/dev":            "189:133\n",
+			printer + "/idVendor":       "03f0\n",
+			"mnt/usb2/2-1/2-1.4/uevent": "DEVTYPE=usb_device\n",
+			"mnt/usb2/2-1/2-1.4/dev":    "189:133\n",
+			"platform/uevent":           "",
+		},
+	}
+
+	devices, err := newFixtureScanner(t, f).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	got := DedupeByID(devices)
+	if len(got) != len(devices)-1 {
+		t.Fatalf("wanted %d devices got %d", len(devices)-1, len(got))
+	}
+
+	var found *types.Device
+	for _, d := range got {
+		if d.ID() == "c189:133" {
+			if found != nil {
+				t.Fatal("duplicated device kept")
+			}
+			found = d
+		}
+	}
+	if found == nil || found.Devpath != printer {
+		t.Errorf("want the device with more data kept got %v", found)
+	}
+
+	a := &types.Device{Devpath: "a", Attrs: map[string]string{"dev": "4:64"}}
+	b := &types.Device{Devpath: "b", Attrs: map[string]string{"dev": "4:64"}}
+	if got := DedupeByID([]*types.Device{a, b}); len(got) != 1 || got[0] != a {
+		t.Errorf("want ties to keep the first device got %v", got)
+	}
+}
+
+func TestSortBySeqnum(t *testing.T) {
+	usb := "pci0000:00/0000:00:14.0/usb1"
+	f := fixture{
+		devices: map[string]string{
+			usb + "/uevent":             "DEVTYPE=usb_device\n",
+			usb + "/dev":                "189:0\n",
+			usb + "/1-1/uevent":         "DEVTYPE=usb_device\n",
+			usb + "/1-1/dev":            "189:1\n",
+			usb + "/1-1/1-1:1.0/uevent": "DEVTYPE=usb_interface\n",
+			usb + "/1-2/uevent":         "DEVTYPE=usb_device\n",
+			usb + "/1-2/dev":            "189:2\n",
+			"platform/uevent":           "",
+		},
+		udev: map[string]string{
+			"c189:0": "E:SEQNUM=1042\n",
+			"c189:1": "E:SEQNUM=987\n",
+			"c189:2": "E:SEQNUM=9999\n",
+		},
+	}
+
+	devices, err := newFixtureScanner(t, f).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	SortBySeqnum(devices)
+
+	want := []string{
+		usb + "/1-1",
+		usb,
+		usb + "/1-2",
+		// Devices without a SEQNUM keep their order.
+		"pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0",
+		"platform",
+	}
+	if got := devpaths(devices); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
+
+func TestSortBySysnum(t *testing.T) {
+	var devices []*types.Device
+	for _, name := range []string{"event10", "sda10", "event2", "sda", "sda2", "event1", "uinput"} {
+		d := &types.Device{Devpath: "virtual/" + name}
+		setSysname(d)
+		devices = append(devices, d)
+	}
+
+	SortBySysnum(devices)
+
+	var got []string
+	for _, d := range devices {
+		got = append(got, d.Sysname)
+	}
+
+	want := []string{"event1", "event2", "event10", "sda", "sda2", "sda10", "uinput"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
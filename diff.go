@@ -0,0 +1,159 @@
+package libudev
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// TreesEqual reports whether a and b hold the same devices, with the same
+// properties and tree links. Devices are identified by their Devpath, and
+// both the listed devices and their descendants are compared, so a and b
+// can be either the result of a scan or just its roots. The order of the
+// devices, and of their Tags and Links, is not significant.
+//
+// It short-circuits on the first difference, so it is a cheap check to
+// run before computing a full Diff, which reports no changes for equal
+// trees. The reverse does not hold, as Diff only compares Env and Attrs.
+func TreesEqual(a, b []*types.Device) bool {
+	ia, ib := indexTree(a), indexTree(b)
+	if len(ia) != len(ib) {
+		return false
+	}
+
+	for devpath, da := range ia {
+		db, ok := ib[devpath]
+		if !ok || !devicesEqual(da, db) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Diff compares two scans, keying their devices by Devpath, and returns
+// the devices only found in new as added, those only found in old as
+// removed, and those found in both but with a different Env or Attrs as
+// changed (as found in new). As with TreesEqual, the descendants of the
+// listed devices are also compared. Each slice is sorted by Devpath.
+func Diff(old, new []*types.Device) (added, removed, changed []*types.Device) {
+	oldIndex, newIndex := indexTree(old), indexTree(new)
+
+	for devpath, dn := range newIndex {
+		do, ok := oldIndex[devpath]
+		switch {
+		case !ok:
+			added = append(added, dn)
+		case !maps.Equal(do.Env, dn.Env) || !maps.Equal(do.Attrs, dn.Attrs):
+			changed = append(changed, dn)
+		}
+	}
+
+	for devpath, do := range oldIndex {
+		if _, ok := newIndex[devpath]; !ok {
+			removed = append(removed, do)
+		}
+	}
+
+	for _, ds := range [][]*types.Device{added, removed, changed} {
+		slices.SortFunc(ds, func(a, b *types.Device) int {
+			return strings.Compare(a.Devpath, b.Devpath)
+		})
+	}
+
+	return added, removed, changed
+}
+
+// indexTree returns the devices and all their descendants keyed by
+// Devpath.
+func indexTree(devices []*types.Device) map[string]*types.Device {
+	index := map[string]*types.Device{}
+
+	var visit func(d *types.Device)
+	visit = func(d *types.Device) {
+		if _, ok := index[d.Devpath]; ok {
+			return
+		}
+		index[d.Devpath] = d
+
+		for _, c := range d.Children {
+			visit(c)
+		}
+	}
+
+	for _, d := range devices {
+		visit(d)
+	}
+
+	return index
+}
+
+// devicesEqual reports whether a and b have the same properties, and the
+// same parent and children, by Devpath.
+func devicesEqual(a, b *types.Device) bool {
+	if a.Devpath != b.Devpath ||
+		a.Sysname != b.Sysname ||
+		a.Sysnum != b.Sysnum ||
+		a.Subsystem != b.Subsystem ||
+		a.Driver != b.Driver ||
+		a.UsecInitialized != b.UsecInitialized ||
+		a.VendorID != b.VendorID ||
+		a.ProductID != b.ProductID ||
+		a.Synthetic != b.Synthetic {
+		return false
+	}
+
+	if !maps.Equal(a.Env, b.Env) || !maps.Equal(a.Attrs, b.Attrs) {
+		return false
+	}
+
+	if !sameElements(a.Tags, b.Tags) || !sameElements(a.Links, b.Links) {
+		return false
+	}
+
+	if !slices.EqualFunc(a.Descriptors, b.Descriptors, func(x, y types.USBDescriptor) bool {
+		return bytes.Equal(x.Raw, y.Raw)
+	}) {
+		return false
+	}
+
+	if devpathOf(a.Parent) != devpathOf(b.Parent) {
+		return false
+	}
+
+	return sameElements(devpaths(a.Children), devpaths(b.Children))
+}
+
+// sameElements reports whether a and b hold the same elements, in any
+// order.
+func sameElements(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
+
+func devpathOf(d *types.Device) string {
+	if d == nil {
+		return ""
+	}
+
+	return d.Devpath
+}
+
+func devpaths(devices []*types.Device) []string {
+	paths := make([]string, len(devices))
+	for i, d := range devices {
+		paths[i] = d.Devpath
+	}
+
+	return paths
+}
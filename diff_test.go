@@ -0,0 +1,128 @@
+package libudev
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestTreesEqual(t *testing.T) {
+	scan := func() []*types.Device {
+		s := newDemoScanner(t)
+		devices, err := s.ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+		return devices
+	}
+
+	a, b := scan(), scan()
+	if !TreesEqual(a, b) {
+		t.Fatal("want identical scans to be equal")
+	}
+
+	roots := Roots(a)
+	if !TreesEqual(roots, b) {
+		t.Error("want roots to be equal to the full scan")
+	}
+
+	mouse := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"
+	tests := map[string]func(d *types.Device){
+		"env":    func(d *types.Device) { d.Env["DEVTYPE"] = "changed" },
+		"attr":   func(d *types.Device) { d.Attrs["busnum"] = "9" },
+		"tag":    func(d *types.Device) { d.Tags = append(d.Tags, "extra") },
+		"link":   func(d *types.Device) { d.Links = append(d.Links, "extra") },
+		"driver": func(d *types.Device) { d.Driver = "changed" },
+		"child":  func(d *types.Device) { d.Children = d.Children[1:] },
+		"parent": func(d *types.Device) { d.Parent = d.Parent.Parent },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := scan()
+			mutate(findDevice(t, b, mouse))
+
+			if TreesEqual(a, b) {
+				t.Error("want near-identical trees to differ")
+			}
+		})
+	}
+
+	// An extra device in b, reachable from the listed devices.
+	b = scan()
+	printer := findDevice(t, b, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4")
+	printer.Children = append(printer.Children, &types.Device{Devpath: printer.Devpath + "/extra", Parent: printer})
+	if TreesEqual(a, b) || TreesEqual(b, a) {
+		t.Error("want tree with an extra device to differ")
+	}
+
+	b = scan()
+	reversed := make([]*types.Device, len(b))
+	for i, d := range b {
+		reversed[len(b)-1-i] = d
+	}
+	if !TreesEqual(a, reversed) {
+		t.Error("want device order not to be significant")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	scan := func() []*types.Device {
+		s := newDemoScanner(t)
+		devices, err := s.ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+		return devices
+	}
+
+	old, new := scan(), scan()
+	added, removed, changed := Diff(old, new)
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Fatalf("want no changes got added %v removed %v changed %v", added, removed, changed)
+	}
+
+	mouse := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"
+	printer := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4"
+	lp0 := printer + "/2-1.4:1.0/usbmisc/lp0"
+
+	// Unplug the printer and plug a new device, while updating the
+	// mouse attrs.
+	var devices []*types.Device
+	for _, d := range new {
+		if !strings.HasPrefix(d.Devpath, printer) {
+			devices = append(devices, d)
+		}
+	}
+	hub := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1")
+	hub.Children = slices.DeleteFunc(hub.Children, func(d *types.Device) bool { return d.Devpath == printer })
+	devices = append(devices, &types.Device{Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.3"})
+	findDevice(t, devices, mouse).Attrs["bConfigurationValue"] = "2"
+	// Pointers and non Env/Attrs fields are not compared.
+	findDevice(t, devices, mouse).Tags = nil
+
+	added, removed, changed = Diff(old, devices)
+
+	if got := devpaths(added); !slices.Equal(got, []string{"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.3"}) {
+		t.Errorf("want added 2-1.3 got %v", got)
+	}
+
+	got := devpaths(removed)
+	if len(got) == 0 || got[0] != printer || !slices.Contains(got, lp0) {
+		t.Errorf("want printer and its descendants removed got %v", got)
+	}
+	for _, p := range got {
+		if !strings.HasPrefix(p, printer) {
+			t.Errorf("device %q removed incorrectly", p)
+		}
+	}
+
+	if got := devpaths(changed); !slices.Equal(got, []string{mouse}) {
+		t.Errorf("want changed mouse got %v", got)
+	}
+	if changed[0] != findDevice(t, devices, mouse) {
+		t.Error("want the changed device from the new scan")
+	}
+}
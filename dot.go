@@ -0,0 +1,50 @@
+package libudev
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// dotEscaper escapes strings to be quoted in the DOT language.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteDOT writes the device tree to w as a Graphviz digraph, which can
+// be rendered with e.g. `dot -Tpng`. Nodes are identified by Devpath and
+// labelled with their Sysname and Subsystem, with edges from parents to
+// children. The tree is traversed with Walk, so each device is written
+// once, even in trees that link back on themselves.
+func WriteDOT(w io.Writer, devices []*types.Device) error {
+	if _, err := io.WriteString(w, "digraph devices {\n"); err != nil {
+		return err
+	}
+
+	err := Walk(devices, func(d *types.Device, _ int) error {
+		label := dotEscaper.Replace(d.Sysname)
+		if d.Subsystem != "" {
+			label += `\n` + dotEscaper.Replace(d.Subsystem)
+		}
+
+		_, err := fmt.Fprintf(w, "\t\"%s\" [label=\"%s\"];\n", dotEscaper.Replace(d.Devpath), label)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range d.Children {
+			_, err := fmt.Fprintf(w, "\t\"%s\" -> \"%s\";\n", dotEscaper.Replace(d.Devpath), dotEscaper.Replace(c.Devpath))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
@@ -0,0 +1,55 @@
+package libudev
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestWriteDOT(t *testing.T) {
+	hub := &types.Device{Devpath: "pci0000:00/0000:00:14.0/usb1/1-1", Sysname: "1-1", Subsystem: "usb"}
+	intf := &types.Device{Devpath: "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0", Sysname: "1-1:1.0", Subsystem: "usb", Parent: hub}
+	odd := &types.Device{Devpath: `virtual/misc/a"b\c`, Sysname: `a"b\c`}
+	hub.Children = []*types.Device{intf}
+	// A child linking back to its parent must not loop.
+	intf.Children = []*types.Device{hub}
+
+	var b strings.Builder
+	if err := WriteDOT(&b, []*types.Device{hub, intf, odd}); err != nil {
+		t.Fatal("failed to write DOT", err)
+	}
+
+	want := `digraph devices {
+	"pci0000:00/0000:00:14.0/usb1/1-1" [label="1-1\nusb"];
+	"pci0000:00/0000:00:14.0/usb1/1-1" -> "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0";
+	"pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0" [label="1-1:1.0\nusb"];
+	"pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0" -> "pci0000:00/0000:00:14.0/usb1/1-1";
+	"virtual/misc/a\"b\\c" [label="a\"b\\c"];
+}
+`
+	if b.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestWriteDOTDemoTree(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	var b strings.Builder
+	if err := WriteDOT(&b, devices); err != nil {
+		t.Fatal("failed to write DOT", err)
+	}
+
+	out := b.String()
+	if n := strings.Count(out, "[label="); n != len(devices) {
+		t.Errorf("wanted %d nodes got %d", len(devices), n)
+	}
+	if n := strings.Count(out, " -> "); n != len(devices)-len(Roots(devices)) {
+		t.Errorf("wanted %d edges got %d", len(devices)-len(Roots(devices)), n)
+	}
+}
@@ -0,0 +1,32 @@
+package libudev
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+
+	"github.com/qubesome/libudev/types"
+)
+
+var drmCardPattern = regexp.MustCompile(`^card[0-9]+$`)
+
+// ListDisplays returns the DRM cards (e.g. `card0`) found in the devices
+// tree. The connectors of each card (e.g. `card0-HDMI-A-1`) are available
+// in its Children, and their status can be obtained with ConnectorStatus.
+func (s *scanner) ListDisplays() ([]*types.Device, error) {
+	devices, err := s.scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []*types.Device
+	for _, d := range devices {
+		if d.Subsystem != "drm" || !drmCardPattern.MatchString(filepath.Base(d.Devpath)) {
+			continue
+		}
+
+		cards = append(cards, d)
+	}
+
+	return cards, nil
+}
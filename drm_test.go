@@ -0,0 +1,73 @@
+package libudev
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/matcher"
+)
+
+func TestListDisplays(t *testing.T) {
+	card := "pci0000:00/0000:00:02.0/drm/card0"
+	f := fixture{
+		devices: map[string]string{
+			card + "/uevent":                                "MAJOR=226\nMINOR=0\nDEVNAME=dri/card0\nDEVTYPE=drm_minor\n",
+			card + "/dev":                                   "226:0\n",
+			card + "/card0-HDMI-A-1/uevent":                 "DEVTYPE=drm_connector\n",
+			card + "/card0-HDMI-A-1/status":                 "connected\n",
+			card + "/card0-DP-1/uevent":                     "DEVTYPE=drm_connector\n",
+			card + "/card0-DP-1/status":                     "disconnected\n",
+			"pci0000:00/0000:00:02.0/drm/renderD128/uevent": "MAJOR=226\nMINOR=128\nDEVNAME=dri/renderD128\nDEVTYPE=drm_minor\n",
+			"pci0000:00/0000:00:01.0/uevent":                "PCI_SLOT_NAME=0000:00:01.0\n",
+		},
+		links: map[string]string{
+			card + "/subsystem":                                "../../../../../class/drm",
+			card + "/card0-HDMI-A-1/subsystem":                 "../../../../../../class/drm",
+			card + "/card0-DP-1/subsystem":                     "../../../../../../class/drm",
+			"pci0000:00/0000:00:02.0/drm/renderD128/subsystem": "../../../../../class/drm",
+		},
+	}
+
+	// The matcher and WithMaxDevices only apply to ScanDevices, so they
+	// must not stop the walk before the cards are found.
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleDevpath("^pci0000:00/0000:00:01.0$"))
+
+	for _, opts := range [][]Option{nil, {WithMatcher(m), WithMaxDevices(1)}} {
+		testListDisplays(t, newFixtureScanner(t, f, opts...), card)
+	}
+}
+
+func testListDisplays(t *testing.T, s *scanner, card string) {
+	t.Helper()
+
+	displays, err := s.ListDisplays()
+	if err != nil {
+		t.Fatal("failed to list displays", err)
+	}
+
+	if len(displays) != 1 {
+		t.Fatalf("wanted 1 display got %d", len(displays))
+	}
+
+	if displays[0].Devpath != card {
+		t.Errorf("want Devpath %s got %s", card, displays[0].Devpath)
+	}
+
+	want := map[string]string{
+		card + "/card0-HDMI-A-1": "connected",
+		card + "/card0-DP-1":     "disconnected",
+	}
+	if len(displays[0].Children) != len(want) {
+		t.Fatalf("wanted %d connectors got %d", len(want), len(displays[0].Children))
+	}
+
+	for _, c := range displays[0].Children {
+		if c.ConnectorStatus() != want[c.Devpath] {
+			t.Errorf("want %s status %q got %q", c.Devpath, want[c.Devpath], c.ConnectorStatus())
+		}
+	}
+
+	if displays[0].ConnectorStatus() != "" {
+		t.Errorf("want empty status for card got %q", displays[0].ConnectorStatus())
+	}
+}
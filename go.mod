@@ -1,3 +1,5 @@
 module github.com/qubesome/libudev
 
-go 1.24
+// go 1.25 is needed for os.Root.Readlink, used to resolve the subsystem,
+// driver and other sysfs symlinks within the roots.
+go 1.25
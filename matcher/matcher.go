@@ -1,7 +1,8 @@
 // Package matcher implements a mechanism for filtering devices.
 //
 // Matcher allows you to add multiple filter rules by using the method `AddRule`
-// and effectively filter the list of devices in a single pass.
+// (or `AddRules`) and effectively filter the list of devices in a single pass,
+// or check a single device with `MatchesDevice`.
 //
 // By default, Matcher uses the `AND` comparison strategy, but you can set the filtering strategy to` OR`.
 package matcher
@@ -20,6 +21,13 @@ type Rule interface {
 	Match(device *types.Device) bool
 }
 
+// TreeRule interface for rules that depend on the device tree (e.g. on
+// the device ancestors), which is only fully linked once a scan completes.
+type TreeRule interface {
+	Rule
+	RequiresTree() bool
+}
+
 // Matcher structure of the device filter.
 type Matcher struct {
 	rules    []Rule
@@ -48,9 +56,31 @@ func (m *Matcher) AddRule(rule Rule) {
 	m.rules = append(m.rules, rule)
 }
 
+// AddRules adds several device filtering rules at once.
+//
+// rules - device filtering rules
+func (m *Matcher) AddRules(rules ...Rule) {
+	m.rules = append(m.rules, rules...)
+}
+
+// AddAnyRule adds a group of rules, which matches if any of them matches.
+// With the default `AND` strategy, this allows for `OR` conditions within
+// the otherwise `AND`ed rules.
+//
+// rules - device filtering rules to group
+func (m *Matcher) AddAnyRule(rules ...Rule) {
+	m.AddRule(NewRuleAny(rules...))
+}
+
+// RequiresTree reports whether any of the rules depends on the device
+// tree, in which case the rules can only be evaluated after a full scan.
+func (m *Matcher) RequiresTree() bool {
+	return NewRuleAny(m.rules...).RequiresTree()
+}
+
 func (m *Matcher) Match(devices ...*types.Device) bool {
 	for _, v := range devices {
-		if m.matchDevice(v) {
+		if m.MatchesDevice(v) {
 			return true
 		}
 	}
@@ -61,7 +91,7 @@ func (m *Matcher) Match(devices ...*types.Device) bool {
 func (m *Matcher) Matches(devices []*types.Device) []*types.Device {
 	var ret []*types.Device
 	for _, v := range devices {
-		if !m.matchDevice(v) {
+		if !m.MatchesDevice(v) {
 			continue
 		}
 
@@ -71,7 +101,11 @@ func (m *Matcher) Matches(devices []*types.Device) []*types.Device {
 	return ret
 }
 
-func (m *Matcher) matchDevice(device *types.Device) bool {
+// MatchesDevice reports whether a single device complies with the rules,
+// according to the strategy. A Matcher without rules matches no device.
+//
+// device - device for checking rules
+func (m *Matcher) MatchesDevice(device *types.Device) bool {
 	if len(m.rules) == 0 {
 		return false
 	}
@@ -38,6 +38,37 @@ func TestAddRule(t *testing.T) {
 	}
 }
 
+func TestAddRules(t *testing.T) {
+	m := NewMatcher()
+	m.AddRules(NewRuleDevpath("TEST1"), NewRuleDevpath("TEST2"))
+	m.AddRules()
+	if len(m.rules) != 2 {
+		t.Fatal("Failed to add two rules")
+	}
+}
+
+func TestMatchesDevice(t *testing.T) {
+	devices := getDemoDevices()
+
+	m := NewMatcher()
+	if m.MatchesDevice(devices[0]) {
+		t.Fatal("Empty rules Matcher matched a device")
+	}
+
+	m.AddRules(NewRuleDevpath("devpaht-.+"), NewRuleEnv("ENV-2", "123"))
+	if !m.MatchesDevice(devices[0]) {
+		t.Fatal("Could not match device `devpaht-1`")
+	}
+	if m.MatchesDevice(devices[1]) {
+		t.Fatal("Device `devpaht-2` was matched incorrectly")
+	}
+
+	m.SetStrategy(StrategyOr)
+	if !m.MatchesDevice(devices[1]) {
+		t.Fatal("Could not match device `devpaht-2`")
+	}
+}
+
 func TestMatchDefault(t *testing.T) {
 	devices := getDemoDevices()
 
@@ -148,3 +179,22 @@ func getDemoDevices() []*types.Device {
 		},
 	}
 }
+
+func TestRequiresTree(t *testing.T) {
+	m := NewMatcher()
+	m.AddRule(NewRuleDevpath("devpaht-1"))
+	if m.RequiresTree() {
+		t.Fatal("Rules do not require the device tree")
+	}
+
+	m.AddRule(treeRule{})
+	if !m.RequiresTree() {
+		t.Fatal("Rules require the device tree")
+	}
+}
+
+type treeRule struct{}
+
+func (treeRule) Match(*types.Device) bool { return true }
+
+func (treeRule) RequiresTree() bool { return true }
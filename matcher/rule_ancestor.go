@@ -0,0 +1,33 @@
+package matcher
+
+import (
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleAncestorSubsystem structure of the filtering rule by the
+// `Subsystem` of the device or any of its ancestors, akin to the udev
+// `SUBSYSTEMS` key.
+type RuleAncestorSubsystem struct {
+	subsystem string
+}
+
+// NewRuleAncestorSubsystem creates a new instance of the filtering rule
+// by the `Subsystem` of the device ancestors (e.g. `usb`). As in udev,
+// the device itself is included, so a `usb` device matches along with
+// its interfaces and endpoints.
+func NewRuleAncestorSubsystem(subsystem string) *RuleAncestorSubsystem {
+	return &RuleAncestorSubsystem{subsystem: subsystem}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleAncestorSubsystem) Match(device *types.Device) bool {
+	return device.Subsystem == m.subsystem || device.GetParentWithSubsystem(m.subsystem) != nil
+}
+
+// RequiresTree reports that the rule depends on the device tree, as it
+// walks the device ancestors.
+func (m *RuleAncestorSubsystem) RequiresTree() bool {
+	return true
+}
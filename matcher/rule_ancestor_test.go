@@ -0,0 +1,49 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleAncestorSubsystem(t *testing.T) {
+	r := NewRuleAncestorSubsystem("TEST")
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+
+	_, ok = interface{}(r).(TreeRule)
+	if !ok || !r.RequiresTree() {
+		t.Fatal("Structure does not require the tree")
+	}
+}
+
+func TestMatchAncestorSubsystem(t *testing.T) {
+	usb := &types.Device{Devpath: "usb1/1-1", Subsystem: "usb"}
+	intf := &types.Device{Devpath: "usb1/1-1/1-1:1.0", Subsystem: "usb", Parent: usb}
+	dv1 := &types.Device{Devpath: "usb1/1-1/1-1:1.0/ep_81", Subsystem: "usb_endpoint", Parent: intf}
+	dv2 := &types.Device{Devpath: "platform/serial8250", Subsystem: "platform"}
+
+	r1 := NewRuleAncestorSubsystem("usb")
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if !r1.Match(usb) {
+		t.Fatal("Could not find device `usb`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	r2 := NewRuleAncestorSubsystem("usb_endpoint")
+	if !r2.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r2.Match(intf) {
+		t.Fatal("The device `intf` was found incorrectly")
+	}
+}
@@ -0,0 +1,43 @@
+package matcher
+
+import (
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleAny structure of the filtering rule grouping other rules, which
+// matches if any of them matches.
+type RuleAny struct {
+	rules []Rule
+}
+
+// NewRuleAny creates a new instance of the filtering rule that matches
+// devices complying with at least one of rules. As it is a Rule itself,
+// groups can be nested and combined with any other rule.
+func NewRuleAny(rules ...Rule) *RuleAny {
+	return &RuleAny{rules: rules}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleAny) Match(device *types.Device) bool {
+	for _, v := range m.rules {
+		if v.Match(device) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequiresTree reports whether any of the grouped rules depends on the
+// device tree.
+func (m *RuleAny) RequiresTree() bool {
+	for _, v := range m.rules {
+		if r, ok := v.(TreeRule); ok && r.RequiresTree() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package matcher
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleAny(t *testing.T) {
+	r := NewRuleAny(NewRuleDevpath("TEST"))
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	logitech := &types.Device{
+		Env:   map[string]string{"SUBSYSTEM": "usb"},
+		Attrs: map[string]string{"idVendor": "046d"},
+	}
+	razer := &types.Device{
+		Env:   map[string]string{"SUBSYSTEM": "usb"},
+		Attrs: map[string]string{"idVendor": "1532"},
+	}
+	intel := &types.Device{
+		Env:   map[string]string{"SUBSYSTEM": "usb"},
+		Attrs: map[string]string{"idVendor": "8087"},
+	}
+	hidraw := &types.Device{
+		Env:   map[string]string{"SUBSYSTEM": "hidraw"},
+		Attrs: map[string]string{"idVendor": "046d"},
+	}
+	devices := []*types.Device{logitech, razer, intel, hidraw}
+
+	m := NewMatcher()
+	m.AddRule(NewRuleEnv("SUBSYSTEM", "^usb$"))
+	m.AddAnyRule(NewRuleAttr("idVendor", "^046d$"), NewRuleAttr("idVendor", "^1532$"))
+	if len(m.Matches(devices)) != 2 {
+		t.Fatal("Not found two devices")
+	}
+
+	m2 := NewMatcher()
+	m2.AddAnyRule(
+		NewRuleNotEnv("SUBSYSTEM", "^usb$"),
+		NewRuleAny(NewRuleRegexpAttr("idVendor", regexp.MustCompile("^80"))),
+	)
+	found := m2.Matches(devices)
+	if len(found) != 2 || found[0] != intel || found[1] != hidraw {
+		t.Fatal("Not found two devices")
+	}
+
+	if NewRuleAny().Match(logitech) {
+		t.Fatal("Empty group matched device")
+	}
+}
+
+func TestRuleAnyRequiresTree(t *testing.T) {
+	r := NewRuleAny(NewRuleDevpath("TEST"))
+	if r.RequiresTree() {
+		t.Fatal("Rules do not require the device tree")
+	}
+
+	r = NewRuleAny(NewRuleDevpath("TEST"), NewRuleAny(treeRule{}))
+	if !r.RequiresTree() {
+		t.Fatal("Rules require the device tree")
+	}
+}
@@ -26,6 +26,15 @@ func NewRuleAttr(attrName, regexpValue string) *RuleAttr {
 	return rule
 }
 
+// NewRuleRegexpAttr creates a new instance of the filtering rule by
+// attributes, matching the value of attrName against a compiled regexp.
+func NewRuleRegexpAttr(attrName string, re *regexp.Regexp) *RuleAttr {
+	return &RuleAttr{
+		attrName: attrName,
+		regexp:   re,
+	}
+}
+
 // Match verifies that the device complies with the rule.
 //
 // device - device for checking rules
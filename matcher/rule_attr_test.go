@@ -1,6 +1,7 @@
 package matcher
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/qubesome/libudev/types"
@@ -44,3 +45,33 @@ func TestMatchAttr(t *testing.T) {
 		t.Fatal("The device `dv1` was found incorrectly")
 	}
 }
+
+func TestMatchRegexpAttr(t *testing.T) {
+	dv1 := &types.Device{
+		Attrs: map[string]string{"product": "USB Optical Mouse"},
+	}
+	dv2 := &types.Device{
+		Attrs: map[string]string{"product": "EHCI Host Controller"},
+	}
+	dv3 := &types.Device{
+		Attrs: map[string]string{},
+	}
+
+	r1 := NewRuleRegexpAttr("product", regexp.MustCompile(".*Optical.*"))
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	r2 := NewRuleRegexpAttr("product", nil)
+	if r2.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+}
@@ -0,0 +1,30 @@
+package matcher
+
+import (
+	"path/filepath"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleDevnameBase structure of the filtering rule by the base name of `DEVNAME`.
+type RuleDevnameBase struct {
+	name string
+}
+
+// NewRuleDevnameBase creates a new instance of the filtering rule by the
+// base name of `DEVNAME` (e.g. `lp0` for `usb/lp0`).
+func NewRuleDevnameBase(name string) *RuleDevnameBase {
+	return &RuleDevnameBase{name: name}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleDevnameBase) Match(device *types.Device) bool {
+	devname, ok := device.Env["DEVNAME"]
+	if !ok || devname == "" {
+		return false
+	}
+
+	return filepath.Base(devname) == m.name
+}
@@ -0,0 +1,50 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleDevnameBase(t *testing.T) {
+	r := NewRuleDevnameBase("TEST")
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchDevnameBase(t *testing.T) {
+	dv1 := &types.Device{
+		Env: map[string]string{"DEVNAME": "usb/lp0"},
+	}
+	dv2 := &types.Device{
+		Env: map[string]string{"DEVNAME": "lp0"},
+	}
+	dv3 := &types.Device{
+		Env: map[string]string{},
+	}
+
+	r1 := NewRuleDevnameBase("lp0")
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if !r1.Match(dv2) {
+		t.Fatal("Could not find device `dv2`")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	r2 := NewRuleDevnameBase("usb")
+	if r2.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+
+	r3 := NewRuleDevnameBase("")
+	if r3.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+}
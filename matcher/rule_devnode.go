@@ -0,0 +1,39 @@
+package matcher
+
+import (
+	"os"
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleDevnodeExists structure of the filtering rule by the existence of
+// the device node.
+type RuleDevnodeExists struct {
+	devRoot *os.Root
+}
+
+// NewRuleDevnodeExists creates a new instance of the filtering rule by
+// the existence of the device node within devRoot, which usually points
+// to /dev. This allows to tell apart devices known by the kernel from
+// the ones that can actually be opened.
+func NewRuleDevnodeExists(devRoot *os.Root) *RuleDevnodeExists {
+	return &RuleDevnodeExists{devRoot: devRoot}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleDevnodeExists) Match(device *types.Device) bool {
+	if m.devRoot == nil {
+		return false
+	}
+
+	devnode := device.Devnode()
+	if devnode == "" {
+		return false
+	}
+
+	_, err := m.devRoot.Stat(strings.TrimPrefix(devnode, "/dev/"))
+	return err == nil
+}
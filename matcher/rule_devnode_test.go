@@ -0,0 +1,68 @@
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleDevnodeExists(t *testing.T) {
+	r := NewRuleDevnodeExists(nil)
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchDevnodeExists(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "input"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "input/event2"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	devRoot, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devRoot.Close()
+
+	dv1 := &types.Device{
+		Env: map[string]string{"DEVNAME": "input/event2"},
+	}
+	dv2 := &types.Device{
+		Env: map[string]string{"DEVNAME": "input/event3"},
+	}
+	dv3 := &types.Device{
+		Env: map[string]string{},
+	}
+	dv4 := &types.Device{
+		Env: map[string]string{"DEVNAME": "/dev/input/event2"},
+	}
+
+	r1 := NewRuleDevnodeExists(devRoot)
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	if !r1.Match(dv4) {
+		t.Fatal("Could not find device `dv4`")
+	}
+
+	r2 := NewRuleDevnodeExists(nil)
+	if r2.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+}
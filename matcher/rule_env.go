@@ -26,6 +26,15 @@ func NewRuleEnv(envName, regexpValue string) *RuleEnv {
 	return rule
 }
 
+// NewRuleRegexpEnv creates a new instance of the filtering rule by
+// `Env`, matching the value of envName against a compiled regexp.
+func NewRuleRegexpEnv(envName string, re *regexp.Regexp) *RuleEnv {
+	return &RuleEnv{
+		envName: envName,
+		regexp:  re,
+	}
+}
+
 // Match verifies that the device complies with the rule.
 //
 // device - device for checking rules
@@ -1,6 +1,7 @@
 package matcher
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/qubesome/libudev/types"
@@ -44,3 +45,40 @@ func TestMatchEnv(t *testing.T) {
 		t.Fatal("The device `dv1` was found incorrectly")
 	}
 }
+
+func TestMatchRegexpEnv(t *testing.T) {
+	dv1 := &types.Device{
+		Env: map[string]string{"ID_MODEL": "USB_Optical_Mouse"},
+	}
+	dv2 := &types.Device{
+		Env: map[string]string{"ID_MODEL": "EHCI_Host_Controller"},
+	}
+	dv3 := &types.Device{
+		Env: map[string]string{},
+	}
+
+	r1 := NewRuleRegexpEnv("ID_MODEL", regexp.MustCompile(".*Optical.*"))
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	r2 := NewRuleRegexpEnv("ID_MODEL", nil)
+	if r2.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+
+	m := NewMatcher()
+	m.AddRule(r1)
+	m.AddRule(NewRuleEnv("ID_MODEL", "Mouse$"))
+	if len(m.Matches([]*types.Device{dv1, dv2, dv3})) != 1 {
+		t.Fatal("Not found one device")
+	}
+}
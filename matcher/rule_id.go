@@ -0,0 +1,55 @@
+package matcher
+
+import (
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleVendorID structure of the filtering rule by `VendorID`.
+type RuleVendorID struct {
+	id string
+}
+
+// NewRuleVendorID creates a new instance of the filtering rule by
+// `VendorID` (e.g. `046d`). The comparison is case-insensitive.
+func NewRuleVendorID(id string) *RuleVendorID {
+	return &RuleVendorID{id: id}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleVendorID) Match(device *types.Device) bool {
+	return device.VendorID != "" && strings.EqualFold(device.VendorID, m.id)
+}
+
+// RequiresTree reports that the rule depends on the device tree, as
+// VendorID may be inherited from the device ancestors.
+func (m *RuleVendorID) RequiresTree() bool {
+	return true
+}
+
+// RuleProductID structure of the filtering rule by `ProductID`.
+type RuleProductID struct {
+	id string
+}
+
+// NewRuleProductID creates a new instance of the filtering rule by
+// `ProductID` (e.g. `c05b`). The comparison is case-insensitive.
+func NewRuleProductID(id string) *RuleProductID {
+	return &RuleProductID{id: id}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleProductID) Match(device *types.Device) bool {
+	return device.ProductID != "" && strings.EqualFold(device.ProductID, m.id)
+}
+
+// RequiresTree reports that the rule depends on the device tree, as
+// ProductID may be inherited from the device ancestors.
+func (m *RuleProductID) RequiresTree() bool {
+	return true
+}
@@ -0,0 +1,47 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleID(t *testing.T) {
+	var r interface{} = NewRuleVendorID("TEST")
+	if _, ok := r.(TreeRule); !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+
+	r = NewRuleProductID("TEST")
+	if _, ok := r.(TreeRule); !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchID(t *testing.T) {
+	dv1 := &types.Device{VendorID: "046d", ProductID: "c05b"}
+	dv2 := &types.Device{VendorID: "1532", ProductID: "0084"}
+	dv3 := &types.Device{}
+
+	r1 := NewRuleVendorID("046D")
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	r2 := NewRuleProductID("c05b")
+	if !r2.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r2.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	if NewRuleVendorID("").Match(dv3) || NewRuleProductID("").Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+}
@@ -0,0 +1,51 @@
+package matcher
+
+import (
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleNotEnv structure of the negated filtering rule by `Env`.
+type RuleNotEnv struct {
+	rule *RuleEnv
+}
+
+// NewRuleNotEnv creates a new instance of the negated filtering rule by
+// `Env`, which matches devices that either lack envName or whose value
+// does not match regexpValue.
+func NewRuleNotEnv(envName, regexpValue string) *RuleNotEnv {
+	return &RuleNotEnv{rule: NewRuleEnv(envName, regexpValue)}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleNotEnv) Match(device *types.Device) bool {
+	if m.rule.regexp == nil {
+		return false
+	}
+
+	return !m.rule.Match(device)
+}
+
+// RuleNotAttr structure of the negated filtering rule by attributes.
+type RuleNotAttr struct {
+	rule *RuleAttr
+}
+
+// NewRuleNotAttr creates a new instance of the negated filtering rule by
+// attributes, which matches devices that either lack attrName or whose
+// value does not match regexpValue.
+func NewRuleNotAttr(attrName, regexpValue string) *RuleNotAttr {
+	return &RuleNotAttr{rule: NewRuleAttr(attrName, regexpValue)}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleNotAttr) Match(device *types.Device) bool {
+	if m.rule.regexp == nil {
+		return false
+	}
+
+	return !m.rule.Match(device)
+}
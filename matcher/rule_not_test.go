@@ -0,0 +1,86 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleNot(t *testing.T) {
+	var r interface{} = NewRuleNotEnv("TEST", "TEST")
+	if _, ok := r.(Rule); !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+
+	r = NewRuleNotAttr("TEST", "TEST")
+	if _, ok := r.(Rule); !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchNotEnv(t *testing.T) {
+	dv1 := &types.Device{
+		Env: map[string]string{"ID_INPUT_MOUSE": "1"},
+	}
+	dv2 := &types.Device{
+		Env: map[string]string{"ID_INPUT_MOUSE": "0"},
+	}
+	dv3 := &types.Device{
+		Env: map[string]string{},
+	}
+
+	r1 := NewRuleNotEnv("ID_INPUT_MOUSE", "^1$")
+	if r1.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+
+	if !r1.Match(dv2) {
+		t.Fatal("Could not find device `dv2`")
+	}
+
+	if !r1.Match(dv3) {
+		t.Fatal("Could not find device `dv3`")
+	}
+
+	r2 := NewRuleNotEnv("ID_INPUT_MOUSE", "[")
+	if r2.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+}
+
+func TestMatchNotAttr(t *testing.T) {
+	dv1 := &types.Device{
+		Attrs: map[string]string{"removable": "removable"},
+	}
+	dv2 := &types.Device{
+		Attrs: map[string]string{"removable": "fixed"},
+	}
+	dv3 := &types.Device{
+		Attrs: map[string]string{},
+	}
+
+	r1 := NewRuleNotAttr("removable", "^removable$")
+	if r1.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+
+	if !r1.Match(dv2) {
+		t.Fatal("Could not find device `dv2`")
+	}
+
+	if !r1.Match(dv3) {
+		t.Fatal("Could not find device `dv3`")
+	}
+
+	r2 := NewRuleNotAttr("removable", "[")
+	if r2.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	m := NewMatcher()
+	m.AddRule(NewRuleAttr("removable", ".+"))
+	m.AddRule(r1)
+	if len(m.Matches([]*types.Device{dv1, dv2, dv3})) != 1 {
+		t.Fatal("Not found one device")
+	}
+}
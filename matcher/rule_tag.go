@@ -0,0 +1,25 @@
+package matcher
+
+import (
+	"slices"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleTag structure of the filtering rule by udev `Tags`.
+type RuleTag struct {
+	tag string
+}
+
+// NewRuleTag creates a new instance of the filtering rule by udev `Tags`
+// (e.g. `uaccess` or `seat`).
+func NewRuleTag(tag string) *RuleTag {
+	return &RuleTag{tag: tag}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleTag) Match(device *types.Device) bool {
+	return slices.Contains(device.Tags, m.tag)
+}
@@ -0,0 +1,43 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleTag(t *testing.T) {
+	r := NewRuleTag("TEST")
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchTag(t *testing.T) {
+	dv1 := &types.Device{
+		Tags: []string{"seat", "uaccess"},
+	}
+	dv2 := &types.Device{
+		Tags: []string{"systemd"},
+	}
+	dv3 := &types.Device{}
+
+	r1 := NewRuleTag("uaccess")
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	r2 := NewRuleTag("uacc")
+	if r2.Match(dv1) {
+		t.Fatal("The device `dv1` was found incorrectly")
+	}
+}
@@ -0,0 +1,56 @@
+package matcher
+
+import (
+	"strconv"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RuleUSBPortPath structure of the filtering rule by USB port path.
+type RuleUSBPortPath struct {
+	path string
+}
+
+// NewRuleUSBPortPath creates a new instance of the filtering rule by USB
+// port path (e.g. `1-1.2`), matching whatever is plugged into that port.
+func NewRuleUSBPortPath(path string) *RuleUSBPortPath {
+	return &RuleUSBPortPath{path: path}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleUSBPortPath) Match(device *types.Device) bool {
+	return m.path != "" && device.USBPortPath() == m.path
+}
+
+// RuleUSBSpeed structure of the filtering rule by USB device speed.
+type RuleUSBSpeed struct {
+	minMbps int
+}
+
+// NewRuleUSBSpeed creates a new instance of the filtering rule by USB
+// device speed, matching the USB devices whose `speed` attr (in Mbps,
+// e.g. `480` for high-speed) is at least minMbps.
+func NewRuleUSBSpeed(minMbps int) *RuleUSBSpeed {
+	return &RuleUSBSpeed{minMbps: minMbps}
+}
+
+// Match verifies that the device complies with the rule.
+//
+// device - device for checking rules
+func (m *RuleUSBSpeed) Match(device *types.Device) bool {
+	// Network devices also have a `speed` attr, so only USB devices are
+	// considered.
+	if device.Subsystem != "usb" && device.Env["DEVTYPE"] != "usb_device" {
+		return false
+	}
+
+	// Low-speed devices report a fractional speed of `1.5`.
+	speed, err := strconv.ParseFloat(device.Attrs["speed"], 64)
+	if err != nil {
+		return false
+	}
+
+	return speed >= float64(m.minMbps)
+}
@@ -0,0 +1,106 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestNewRuleUSBPortPath(t *testing.T) {
+	r := NewRuleUSBPortPath("TEST")
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchUSBPortPath(t *testing.T) {
+	dv1 := &types.Device{
+		Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2",
+	}
+	dv2 := &types.Device{
+		Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4",
+	}
+	dv3 := &types.Device{
+		Devpath: "pci0000:00/0000:00:1d.0/usb2",
+	}
+
+	r1 := NewRuleUSBPortPath("2-1.2")
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if r1.Match(dv2) {
+		t.Fatal("The device `dv2` was found incorrectly")
+	}
+
+	r2 := NewRuleUSBPortPath("")
+	if r2.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+}
+
+func TestNewRuleUSBSpeed(t *testing.T) {
+	r := NewRuleUSBSpeed(480)
+	_, ok := interface{}(r).(Rule)
+	if !ok {
+		t.Fatal("Structure does not implement interface")
+	}
+}
+
+func TestMatchUSBSpeed(t *testing.T) {
+	usb := func(speed string) *types.Device {
+		return &types.Device{
+			Subsystem: "usb",
+			Attrs:     map[string]string{"speed": speed},
+		}
+	}
+
+	dv1 := usb("480")
+	dv2 := usb("5000")
+	dv3 := usb("12")
+	dv4 := usb("1.5")
+	dv5 := &types.Device{
+		Env:   map[string]string{"DEVTYPE": "usb_device"},
+		Attrs: map[string]string{"speed": "480"},
+	}
+	dv6 := &types.Device{
+		Subsystem: "net",
+		Attrs:     map[string]string{"speed": "1000"},
+	}
+	dv7 := &types.Device{Subsystem: "usb"}
+
+	r1 := NewRuleUSBSpeed(480)
+	if !r1.Match(dv1) {
+		t.Fatal("Could not find device `dv1`")
+	}
+
+	if !r1.Match(dv2) {
+		t.Fatal("Could not find device `dv2`")
+	}
+
+	if r1.Match(dv3) {
+		t.Fatal("The device `dv3` was found incorrectly")
+	}
+
+	if r1.Match(dv4) {
+		t.Fatal("The device `dv4` was found incorrectly")
+	}
+
+	if !r1.Match(dv5) {
+		t.Fatal("Could not find device `dv5`")
+	}
+
+	if r1.Match(dv6) {
+		t.Fatal("The device `dv6` was found incorrectly")
+	}
+
+	if r1.Match(dv7) {
+		t.Fatal("The device `dv7` was found incorrectly")
+	}
+
+	r2 := NewRuleUSBSpeed(1)
+	if !r2.Match(dv4) {
+		t.Fatal("Could not find device `dv4`")
+	}
+}
@@ -0,0 +1,55 @@
+package libudev
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// metricsLabelEscaper escapes label values as required by the Prometheus
+// text exposition format.
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the number of devices per subsystem and per driver
+// to w, as gauges in the Prometheus text exposition format:
+//
+//	udev_devices{subsystem="usb"} 6
+//	udev_devices_by_driver{driver="usb"} 6
+//
+// Devices without a subsystem or driver are counted under an empty
+// label value. Series are sorted by label value.
+func WriteMetrics(w io.Writer, devices []*types.Device) error {
+	subsystems := map[string]int{}
+	drivers := map[string]int{}
+	for _, d := range devices {
+		subsystems[d.Subsystem]++
+		drivers[d.Driver]++
+	}
+
+	err := writeGauge(w, "udev_devices", "Number of devices per subsystem.", "subsystem", subsystems)
+	if err != nil {
+		return err
+	}
+
+	return writeGauge(w, "udev_devices_by_driver", "Number of devices per driver.", "driver", drivers)
+}
+
+func writeGauge(w io.Writer, name, help, label string, counts map[string]int) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range slices.Sorted(maps.Keys(counts)) {
+		_, err := fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, metricsLabelEscaper.Replace(v), counts[v])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
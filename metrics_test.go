@@ -0,0 +1,74 @@
+package libudev
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	hub := "pci0000:00/0000:00:1d.0/usb2/2-1"
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			hub + "/uevent":                                     "SUBSYSTEM=usb\nDEVTYPE=usb_device\nDRIVER=usb\n",
+			hub + "/2-1.2/uevent":                               "SUBSYSTEM=usb\nDEVTYPE=usb_device\nDRIVER=usb\n",
+			hub + "/2-1.2/2-1.2:1.0/uevent":                     "SUBSYSTEM=usb\nDEVTYPE=usb_interface\nDRIVER=usbhid\n",
+			hub + "/2-1.2/2-1.2:1.0/input/input9/uevent":        "SUBSYSTEM=input\n",
+			hub + "/2-1.2/2-1.2:1.0/input/input9/event2/uevent": "SUBSYSTEM=input\nDEVNAME=input/event2\n",
+			"platform/serial8250/tty/ttyS0/uevent":              "SUBSYSTEM=tty\nDEVNAME=ttyS0\n",
+		},
+	})
+
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	var b strings.Builder
+	if err := WriteMetrics(&b, devices); err != nil {
+		t.Fatal("failed to write metrics", err)
+	}
+
+	want := `# HELP udev_devices Number of devices per subsystem.
+# TYPE udev_devices gauge
+udev_devices{subsystem="input"} 2
+udev_devices{subsystem="tty"} 1
+udev_devices{subsystem="usb"} 3
+# HELP udev_devices_by_driver Number of devices per driver.
+# TYPE udev_devices_by_driver gauge
+udev_devices_by_driver{driver=""} 3
+udev_devices_by_driver{driver="usb"} 2
+udev_devices_by_driver{driver="usbhid"} 1
+`
+	if b.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestWriteMetricsEscaping(t *testing.T) {
+	devices := []*types.Device{
+		{Subsystem: "usb", Driver: `a"b`},
+		{Subsystem: "usb", Driver: `c\d`},
+		{Subsystem: "in\nput"},
+	}
+
+	var b strings.Builder
+	if err := WriteMetrics(&b, devices); err != nil {
+		t.Fatal("failed to write metrics", err)
+	}
+
+	want := `# HELP udev_devices Number of devices per subsystem.
+# TYPE udev_devices gauge
+udev_devices{subsystem="in\nput"} 1
+udev_devices{subsystem="usb"} 2
+# HELP udev_devices_by_driver Number of devices per driver.
+# TYPE udev_devices_by_driver gauge
+udev_devices_by_driver{driver=""} 1
+udev_devices_by_driver{driver="a\"b"} 1
+udev_devices_by_driver{driver="c\\d"} 1
+`
+	if b.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, b.String())
+	}
+}
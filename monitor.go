@@ -0,0 +1,197 @@
+package libudev
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/qubesome/libudev/types"
+)
+
+const (
+	// maxEventSize is the size of the buffer used to read uevents,
+	// which is well above the kernel's UEVENT_BUFFER_SIZE.
+	maxEventSize = 64 * 1024
+)
+
+// Event represents a uevent emitted by the kernel. Action is one of
+// `add`, `remove`, `change`, `move`, `online`, `offline`, `bind` or
+// `unbind`.
+type Event struct {
+	Action string
+	Device *types.Device
+}
+
+// Monitor listens to the kernel uevents for hotplug events.
+type Monitor struct {
+	opts *options
+	conn io.ReadCloser
+
+	mu        sync.Mutex
+	started   bool
+	err       error
+	closeOnce sync.Once
+
+	// done is closed by Close, stopping the goroutines of Events.
+	done chan struct{}
+}
+
+// NewMonitor creates a new instance of the uevent monitor, opening a
+// NETLINK_KOBJECT_UEVENT socket. The socket receive buffer size can be
+// set with WithReceiveBufferSize, and WithMatcher can be used so that
+// only events of matching devices are emitted.
+func NewMonitor(opts ...Option) (*Monitor, error) {
+	s := &scanner{opts: &options{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	conn, err := openUeventConn(s.opts.receiveBufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open uevent socket: %w", err)
+	}
+
+	return newMonitor(s.opts, conn), nil
+}
+
+func newMonitor(opts *options, conn io.ReadCloser) *Monitor {
+	return &Monitor{opts: opts, conn: conn, done: make(chan struct{})}
+}
+
+// Events starts listening for uevents, which are sent to the returned
+// channel. The channel is closed and the underlying socket released once
+// ctx is done or Close is called, or when reading from the socket fails,
+// in which case Err returns the error. Events can only be called once per
+// Monitor.
+func (m *Monitor) Events(ctx context.Context) (<-chan Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return nil, errors.New("monitor already started")
+	}
+	m.started = true
+
+	ch := make(chan Event)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := m.Close(); err != nil {
+				m.opts.log().Debug("cannot close uevent socket", "error", err)
+			}
+		case <-m.done:
+		}
+	}()
+
+	go func() {
+		defer close(ch)
+
+		buf := make([]byte, maxEventSize)
+		for {
+			n, err := m.conn.Read(buf)
+			if err != nil {
+				select {
+				case <-m.done:
+					// Reads fail once the socket is closed.
+				case <-ctx.Done():
+				default:
+					m.opts.log().Debug("failed to read uevent", "error", err)
+					m.setErr(err)
+				}
+				return
+			}
+
+			e, err := parseUevent(buf[:n])
+			if err != nil {
+				m.opts.log().Debug("failed to parse uevent", "error", err)
+				continue
+			}
+
+			if m.opts.matcher != nil && !m.opts.matcher.Match(e.Device) {
+				continue
+			}
+
+			select {
+			case ch <- *e:
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Err returns the error that stopped the events channel, or nil if it
+// was stopped by cancelling ctx or calling Close. It is only meaningful
+// once the channel returned by Events is closed.
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+func (m *Monitor) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// Close releases the underlying socket, closing the channel returned by
+// Events. It is safe to call Close more than once, and the Monitor is
+// unusable afterwards.
+func (m *Monitor) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		err = m.conn.Close()
+	})
+
+	return err
+}
+
+// parseUevent parses a kernel uevent datagram, which is made of a
+// `ACTION@DEVPATH` header followed by NUL separated `KEY=VALUE` pairs.
+func parseUevent(msg []byte) (*Event, error) {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) == 0 || !bytes.Contains(fields[0], []byte("@")) {
+		return nil, errors.New("invalid uevent header")
+	}
+
+	device := &types.Device{
+		Env:   map[string]string{},
+		Attrs: map[string]string{},
+	}
+
+	for _, f := range fields[1:] {
+		k, v, ok := strings.Cut(string(f), "=")
+		if !ok {
+			continue
+		}
+
+		setEnv(device.Env, k, v)
+	}
+
+	action := device.Env["ACTION"]
+	devpath := device.Env["DEVPATH"]
+	if action == "" || devpath == "" {
+		return nil, errors.New("uevent missing ACTION or DEVPATH")
+	}
+
+	// Keep Devpath relative to /sys/devices, as done by the scanner.
+	device.Devpath = strings.TrimPrefix(strings.TrimPrefix(devpath, "/devices"), "/")
+	setSysname(device)
+	device.Subsystem = device.Env["SUBSYSTEM"]
+	device.Driver = device.Env["DRIVER"]
+	addLinks(device, strings.Fields(device.Env["DEVLINKS"])...)
+
+	return &Event{Action: action, Device: device}, nil
+}
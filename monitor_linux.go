@@ -0,0 +1,72 @@
+//go:build linux
+
+package libudev
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	// kernelEventsGroup is the netlink multicast group of the events
+	// emitted by the kernel, as opposed to the ones relayed by udevd.
+	kernelEventsGroup = 1
+)
+
+// netlinkConn wraps the uevent socket, which is set as non-blocking so
+// that pending reads are interrupted on Close.
+type netlinkConn struct {
+	f *os.File
+}
+
+func openUeventConn(bufSize int) (io.ReadCloser, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK,
+		syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+
+	if bufSize > 0 {
+		// SO_RCVBUFFORCE can go beyond rmem_max, but requires
+		// CAP_NET_ADMIN.
+		err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUFFORCE, bufSize)
+		if err != nil {
+			err = syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, bufSize)
+		}
+		if err != nil {
+			_ = syscall.Close(fd)
+			return nil, err
+		}
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: kernelEventsGroup,
+	})
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+
+	return &netlinkConn{f: os.NewFile(uintptr(fd), "uevent")}, nil
+}
+
+// Read reads a single uevent datagram. Receive buffer overruns, which
+// mean that events were lost, are skipped.
+func (c *netlinkConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.f.Read(b)
+		if errors.Is(err, syscall.ENOBUFS) {
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (c *netlinkConn) Close() error {
+	return c.f.Close()
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package libudev
+
+import (
+	"errors"
+	"io"
+)
+
+func openUeventConn(int) (io.ReadCloser, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,209 @@
+package libudev
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qubesome/libudev/matcher"
+)
+
+func TestParseUevent(t *testing.T) {
+	msg := uevent("add@/devices/pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2",
+		"ACTION=add",
+		"DEVPATH=/devices/pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2",
+		"SUBSYSTEM=usb",
+		"DEVNAME=bus/usb/002/004",
+		"DEVTYPE=usb_device",
+		"PRODUCT=46d/c05b/5400",
+		"SEQNUM=2476")
+
+	e, err := parseUevent(msg)
+	if err != nil {
+		t.Fatal("failed to parse uevent", err)
+	}
+
+	if e.Action != "add" {
+		t.Errorf("want Action %q got %q", "add", e.Action)
+	}
+	if e.Device.Devpath != "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2" {
+		t.Errorf("want Devpath relative to devices root got %q", e.Device.Devpath)
+	}
+	if e.Device.Sysname != "2-1.2" || e.Device.Sysnum != "2" {
+		t.Errorf("want Sysname, Sysnum %q, %q got %q, %q", "2-1.2", "2", e.Device.Sysname, e.Device.Sysnum)
+	}
+	if e.Device.Subsystem != "usb" {
+		t.Errorf("want Subsystem %q got %q", "usb", e.Device.Subsystem)
+	}
+	if e.Device.Env["DEVNAME"] != "bus/usb/002/004" {
+		t.Errorf("want DEVNAME %q got %q", "bus/usb/002/004", e.Device.Env["DEVNAME"])
+	}
+	if e.Device.Attrs == nil {
+		t.Error("want non-nil Attrs")
+	}
+
+	invalid := [][]byte{
+		nil,
+		[]byte("libudev\x00\xfe\xed\xca\xfe"),
+		uevent("add@/devices/foo", "SUBSYSTEM=usb"),
+	}
+	for _, msg := range invalid {
+		if _, err := parseUevent(msg); err == nil {
+			t.Errorf("want error parsing %q", msg)
+		}
+	}
+}
+
+func TestMonitorEvents(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleEnv("SUBSYSTEM", "^input$"))
+
+	s := &scanner{opts: &options{}}
+	WithMatcher(m)(s)
+
+	conn := &fakeConn{
+		msgs: [][]byte{
+			uevent("add@/devices/virtual/input/input9", "ACTION=add", "DEVPATH=/devices/virtual/input/input9", "SUBSYSTEM=input"),
+			[]byte("garbage"),
+			uevent("bind@/devices/virtual/misc/uinput", "ACTION=bind", "DEVPATH=/devices/virtual/misc/uinput", "SUBSYSTEM=misc"),
+			uevent("remove@/devices/virtual/input/input9", "ACTION=remove", "DEVPATH=/devices/virtual/input/input9", "SUBSYSTEM=input"),
+		},
+		closed: make(chan struct{}),
+	}
+	mon := newMonitor(s.opts, conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := mon.Events(ctx)
+	if err != nil {
+		t.Fatal("failed to start monitor", err)
+	}
+
+	if _, err := mon.Events(ctx); err == nil {
+		t.Error("want error when starting monitor twice")
+	}
+
+	for _, want := range []string{"add", "remove"} {
+		e := <-events
+		if e.Action != want {
+			t.Errorf("want Action %q got %q", want, e.Action)
+		}
+		if e.Device.Devpath != "virtual/input/input9" {
+			t.Errorf("want Devpath %q got %q", "virtual/input/input9", e.Device.Devpath)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("want events channel closed after cancel")
+	}
+	<-conn.closed
+
+	if err := mon.Err(); err != nil {
+		t.Errorf("want no error after cancel got %v", err)
+	}
+}
+
+func TestMonitorClose(t *testing.T) {
+	s := &scanner{opts: &options{}}
+	conn := &fakeConn{
+		msgs:   [][]byte{uevent("add@/devices/virtual/misc/uinput", "ACTION=add", "DEVPATH=/devices/virtual/misc/uinput")},
+		closed: make(chan struct{}),
+	}
+	mon := newMonitor(s.opts, conn)
+
+	events, err := mon.Events(context.Background())
+	if err != nil {
+		t.Fatal("failed to start monitor", err)
+	}
+
+	if e := <-events; e.Action != "add" {
+		t.Errorf("want Action %q got %q", "add", e.Action)
+	}
+
+	if err := mon.Close(); err != nil {
+		t.Fatal("failed to close monitor", err)
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("want events channel closed after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel not closed after Close")
+	}
+
+	if err := mon.Err(); err != nil {
+		t.Errorf("want no error after Close got %v", err)
+	}
+}
+
+func TestMonitorReadError(t *testing.T) {
+	s := &scanner{opts: &options{}}
+	boom := errors.New("boom")
+	mon := newMonitor(s.opts, failingConn{err: boom})
+
+	events, err := mon.Events(context.Background())
+	if err != nil {
+		t.Fatal("failed to start monitor", err)
+	}
+	defer mon.Close()
+
+	for range events {
+	}
+
+	if err := mon.Err(); !errors.Is(err, boom) {
+		t.Errorf("want read error got %v", err)
+	}
+}
+
+func TestNewMonitor(t *testing.T) {
+	m, err := NewMonitor(WithReceiveBufferSize(1024 * 1024))
+	if err != nil {
+		t.Skip("netlink sockets not available:", err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal("failed to close monitor", err)
+	}
+}
+
+func uevent(header string, env ...string) []byte {
+	return []byte(header + "\x00" + strings.Join(env, "\x00") + "\x00")
+}
+
+// fakeConn returns msgs one per Read, and then blocks until closed.
+type fakeConn struct {
+	msgs   [][]byte
+	closed chan struct{}
+}
+
+func (c *fakeConn) Read(b []byte) (int, error) {
+	if len(c.msgs) == 0 {
+		<-c.closed
+		return 0, io.EOF
+	}
+
+	n := copy(b, c.msgs[0])
+	c.msgs = c.msgs[1:]
+	return n, nil
+}
+
+func (c *fakeConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+// failingConn fails every Read with err.
+type failingConn struct {
+	err error
+}
+
+func (c failingConn) Read([]byte) (int, error) { return 0, c.err }
+
+func (c failingConn) Close() error { return nil }
@@ -1,8 +1,10 @@
 package libudev
 
 import (
+	"log/slog"
 	"os"
 	"regexp"
+	"time"
 
 	"github.com/qubesome/libudev/matcher"
 )
@@ -13,9 +15,49 @@ type options struct {
 	matcher *matcher.Matcher
 
 	pathFilterPattern *regexp.Regexp
+	warnOnEmptyFilter bool
+	subsystems        []string
+	maxAttrsPerDevice int
+	maxAttrSize       int
+	attrAllowlist     []string
+	symlinkAttrs      []string
+	concurrency       int
+	attrConcurrency   int
+	maxDevices        int
+	maxDepth          int
+	parseDescriptors  bool
+	syntheticRoot     bool
 
 	devicesRoot  *os.Root
 	udevDataRoot *os.Root
+	classRoot    *os.Root
+
+	receiveBufferSize int
+
+	cacheTTL time.Duration
+
+	errorHandler func(path string, err error)
+
+	logger *slog.Logger
+}
+
+// attrSize returns the size limit set with WithMaxAttrSize, or
+// maxDevSize.
+func (o *options) attrSize() int {
+	if o.maxAttrSize > 0 {
+		return o.maxAttrSize
+	}
+
+	return maxDevSize
+}
+
+// log returns the logger set with WithLogger, or slog.Default.
+func (o *options) log() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+
+	return slog.Default()
 }
 
 // WithPathFilterPattern sets a pattern to filter out device paths that
@@ -23,6 +65,11 @@ type options struct {
 // the scanner won't deal with files (and devices) that do not match the
 // regex.
 //
+// The pattern is matched against the full path of each device (e.g.
+// `pci0000:00/0000:00:1d.0/usb2/2-1`), relative to the devices root.
+// Directories are always descended into, so a matching device nested
+// under a non-matching one is still found.
+//
 // For example, when querying USB devices, this could be used:
 // libudev.WithPathFilterPattern(regexp.MustCompile("(?i)^.*pci0000:00.*usb.*"))
 func WithPathFilterPattern(p *regexp.Regexp) Option {
@@ -31,6 +78,26 @@ func WithPathFilterPattern(p *regexp.Regexp) Option {
 	}
 }
 
+// WithWarnOnEmptyFilter logs a warning when the pattern set with
+// WithPathFilterPattern matches no devices, which usually means that
+// the pattern is wrong.
+func WithWarnOnEmptyFilter(warn bool) Option {
+	return func(o *scanner) {
+		o.opts.warnOnEmptyFilter = warn
+	}
+}
+
+// WithSubsystemFilter sets the subsystems (e.g. `input` and `hidraw`) of
+// the devices returned by a scan. All devices are still linked into the
+// tree, so the Parent chain of returned devices is complete even when
+// their ancestors belong to other subsystems. When not provided, devices
+// of all subsystems are returned.
+func WithSubsystemFilter(subsystems ...string) Option {
+	return func(o *scanner) {
+		o.opts.subsystems = subsystems
+	}
+}
+
 // WithMatcher sets a matcher to the scanner, so that only devices matching
 // the rules are returned.
 func WithMatcher(m *matcher.Matcher) Option {
@@ -39,6 +106,136 @@ func WithMatcher(m *matcher.Matcher) Option {
 	}
 }
 
+// WithMaxAttrsPerDevice sets the maximum number of attr files read per
+// device, bounding the work done on devices with lots of attrs. Once the
+// limit is reached, the remaining attrs (in lexical order) are skipped and
+// a debug message is logged. Values lower than 1 mean no limit.
+func WithMaxAttrsPerDevice(n int) Option {
+	return func(o *scanner) {
+		o.opts.maxAttrsPerDevice = n
+	}
+}
+
+// WithMaxAttrSize sets the maximum number of bytes read from each attr
+// file, bounding the memory used by large or pathological files. Longer
+// attrs are truncated to n bytes, and reported to the error handler with
+// ErrAttrTruncated. The same limit applies to the USB `descriptors` files
+// read WithParseDescriptors, which are left undecoded when larger. When
+// not provided, or lower than 1, defaults to 128KB.
+func WithMaxAttrSize(n int) Option {
+	return func(o *scanner) {
+		o.opts.maxAttrSize = n
+	}
+}
+
+// WithAttrAllowlist restricts the attrs read for each device to names
+// (e.g. `idVendor`, `idProduct`, `dev` and `address`), which saves
+// listing the device dirs and reading unneeded files. Nested attrs are
+// named by their relative path (e.g. `power/wakeup`). The `uevent` file
+// is always read for Env, and no names mean that all attrs are read.
+func WithAttrAllowlist(names ...string) Option {
+	return func(o *scanner) {
+		o.opts.attrAllowlist = names
+	}
+}
+
+// WithFollowSymlinkAttrs reads the named symlinks of each device (e.g.
+// `driver` or `device`) as attrs, storing the basename of their targets
+// (e.g. `usbhid`) in Attrs. Names that are missing or are not symlinks
+// are ignored.
+func WithFollowSymlinkAttrs(names ...string) Option {
+	return func(o *scanner) {
+		o.opts.symlinkAttrs = names
+	}
+}
+
+// WithConcurrency sets the number of devices parsed concurrently during
+// a scan. When not provided, or lower than 1, defaults to runtime.NumCPU().
+func WithConcurrency(n int) Option {
+	return func(o *scanner) {
+		o.opts.concurrency = n
+	}
+}
+
+// WithAttrConcurrency sets the number of attr files of a single device
+// that are read concurrently, which helps when reads have high latency.
+// When not provided, or lower than 2, attrs are read sequentially.
+func WithAttrConcurrency(n int) Option {
+	return func(o *scanner) {
+		o.opts.attrConcurrency = n
+	}
+}
+
+// WithMaxDevices sets the maximum number of devices returned by a scan.
+// Values lower than 1 mean no limit.
+//
+// When combined with WithMatcher, the matcher is evaluated while walking
+// the devices root, and the walk stops as soon as n matching devices are
+// found. In that case, only the devices visited so far are linked into
+// the tree, so returned devices may lack Parent links or the VendorID and
+// ProductID inherited from them. Rules that depend on the device tree,
+// such as ancestor-based rules, disable this optimisation and the full
+// tree is scanned before matching.
+func WithMaxDevices(n int) Option {
+	return func(o *scanner) {
+		o.opts.maxDevices = n
+	}
+}
+
+// WithMaxDepth limits the scan to devices at most n path components
+// below the devices root (e.g. `pci0000:00/0000:00:1d.0/usb2` is at a
+// depth of 3), so that deeply nested subtrees are not walked. A value of
+// 0 or less means unlimited, which is the default.
+func WithMaxDepth(n int) Option {
+	return func(o *scanner) {
+		o.opts.maxDepth = n
+	}
+}
+
+// WithParseDescriptors decodes the binary `descriptors` file of USB
+// devices into their Descriptors, which are otherwise left empty.
+// Malformed files are reported to the error handler, without skipping
+// the device.
+func WithParseDescriptors() Option {
+	return func(o *scanner) {
+		o.opts.parseDescriptors = true
+	}
+}
+
+// WithSyntheticRoot links the roots of the devices returned by
+// ScanDevices (those without a Parent) as the Children of a single
+// Synthetic device with an empty Devpath, which is returned first. The
+// root is added once the filters, the matcher and WithMaxDevices have
+// been applied, so it is never matched nor counted, and it is left out
+// when no devices are returned.
+func WithSyntheticRoot(enable bool) Option {
+	return func(o *scanner) {
+		o.opts.syntheticRoot = enable
+	}
+}
+
+// WithErrorHandler sets a function to be called for each error found
+// while scanning, such as devices whose files cannot be read. Those
+// errors do not abort the scan, and the affected devices are usually
+// skipped. Some errors are only reported while keeping the device, such
+// as ErrAttrTruncated and malformed USB descriptors (see
+// WithParseDescriptors). Calls to fn are serialised, even when scanning
+// concurrently.
+func WithErrorHandler(fn func(path string, err error)) Option {
+	return func(o *scanner) {
+		o.opts.errorHandler = fn
+	}
+}
+
+// WithLogger sets the logger used for the diagnostics of the scanner and
+// the monitor, such as files that cannot be closed. When not provided,
+// defaults to slog.Default.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *scanner) {
+		o.opts.logger = l
+	}
+}
+
 // WithDevicesRoot provides a way to set a different os.Root to be used
 // as the Devices dir. When not provided, defaults to an os.Root pointing
 // to /sys/devices.
@@ -56,3 +253,32 @@ func WithUDevDataRoot(r *os.Root) Option {
 		o.opts.udevDataRoot = r
 	}
 }
+
+// WithClassRoot provides a way to set a different os.Root to be used as
+// the class dir by ScanClass. It is expected to be a sibling of the
+// devices root, as in sysfs. When not provided, defaults to an os.Root
+// pointing to /sys/class, which is only opened on the first ScanClass.
+func WithClassRoot(r *os.Root) Option {
+	return func(o *scanner) {
+		o.opts.classRoot = r
+	}
+}
+
+// WithReceiveBufferSize sets the receive buffer size, in bytes, of the
+// socket used by the Monitor. Larger buffers reduce the chances of losing
+// events during bursts (e.g. when plugging a USB hub). When not provided,
+// the system default is used.
+func WithReceiveBufferSize(n int) Option {
+	return func(o *scanner) {
+		o.opts.receiveBufferSize = n
+	}
+}
+
+// WithCacheTTL sets how long the devices cached by a CachedScanner are
+// returned before rescanning. When not provided, or 0, the cache only
+// expires when invalidated.
+func WithCacheTTL(d time.Duration) Option {
+	return func(o *scanner) {
+		o.opts.cacheTTL = d
+	}
+}
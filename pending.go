@@ -0,0 +1,48 @@
+package libudev
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// ListPendingInitialization returns the devices found in sysfs that udev
+// has not processed yet: those without UsecInitialized and without a
+// record in the udev data root. This is a handy signal of udev lagging
+// behind the kernel, e.g. during boot.
+//
+// The udev record is looked up by the device ID, so devices with an ID
+// that does not name a udev record (i.e. without a Subsystem nor a dev
+// number) are never reported.
+func (s *scanner) ListPendingInitialization() ([]*types.Device, error) {
+	devices, err := s.scan(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*types.Device
+	for _, d := range devices {
+		if d.UsecInitialized != "" {
+			continue
+		}
+
+		id := d.ID()
+		if id == d.Devpath {
+			continue
+		}
+
+		_, err := s.opts.udevDataRoot.Stat(id)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		pending = append(pending, d)
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,56 @@
+package libudev
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/qubesome/libudev/matcher"
+)
+
+func TestListPendingInitialization(t *testing.T) {
+	usb := "pci0000:00/0000:00:14.0/usb1/1-1"
+	f := fixture{
+		devices: map[string]string{
+			// Initialized, with a record.
+			usb + "/uevent": "DEVTYPE=usb_device\n",
+			usb + "/dev":    "189:1\n",
+			// Pending, with a dev number.
+			usb + "/1-1.1/uevent": "DEVTYPE=usb_device\n",
+			usb + "/1-1.1/dev":    "189:2\n",
+			// Processed, with a record but not initialized.
+			usb + "/1-1:1.0/uevent": "DEVTYPE=usb_interface\n",
+			// Pending, without a dev number.
+			usb + "/1-1.1/1-1.1:1.0/uevent": "DEVTYPE=usb_interface\n",
+			// Unknown, without a subsystem nor a dev number.
+			"platform/uevent":                "",
+			"pci0000:00/0000:00:01.0/uevent": "",
+		},
+		links: map[string]string{
+			usb + "/subsystem":                 "../../../../../bus/usb",
+			usb + "/1-1.1/subsystem":           "../../../../../../bus/usb",
+			usb + "/1-1:1.0/subsystem":         "../../../../../../bus/usb",
+			usb + "/1-1.1/1-1.1:1.0/subsystem": "../../../../../../../bus/usb",
+		},
+		udev: map[string]string{
+			"c189:1":       "I:1234567\nE:ID_VENDOR=Logitech\n",
+			"+usb:1-1:1.0": "E:ID_USB_INTERFACE_NUM=00\n",
+		},
+	}
+
+	// The matcher and WithMaxDevices only apply to ScanDevices, so they
+	// must not stop the walk before the pending devices are found.
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleDevpath("^pci0000:00/0000:00:01.0$"))
+
+	for _, opts := range [][]Option{nil, {WithMatcher(m), WithMaxDevices(1)}} {
+		devices, err := newFixtureScanner(t, f, opts...).ListPendingInitialization()
+		if err != nil {
+			t.Fatal("failed to list pending devices", err)
+		}
+
+		want := []string{usb + "/1-1.1", usb + "/1-1.1/1-1.1:1.0"}
+		if got := devpaths(devices); !slices.Equal(got, want) {
+			t.Errorf("want %v got %v", want, got)
+		}
+	}
+}
@@ -10,14 +10,18 @@ package libudev
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/qubesome/libudev/types"
 )
@@ -26,61 +30,169 @@ const (
 	maxDevSize = 128 * 1024 // 128KB
 )
 
+// ErrNotDevice is returned when a path has no `uevent` file, and
+// therefore is not a device.
+var ErrNotDevice = errors.New("not a device")
+
+// ErrAttrTruncated is reported to the error handler when an attr file is
+// larger than the limit set with WithMaxAttrSize.
+var ErrAttrTruncated = errors.New("attr truncated")
+
 // Scanner represents a device scanner.
 type scanner struct {
 	opts *options
+
+	// errMu serialises the calls to the error handler.
+	errMu sync.Mutex
+
+	// owned holds the roots opened by the scanner, which are closed by
+	// Close, unlike those provided through options.
+	owned []*os.Root
+
+	// rootMu guards owned, and the class root which is opened lazily by
+	// ScanClass.
+	rootMu sync.Mutex
 }
 
-// NewScanner creates a new instance of the device scanner.
+// NewScanner creates a new instance of the device scanner. Call Close to
+// release the default roots it opens.
 func NewScanner(opts ...Option) (*scanner, error) {
 	s := &scanner{opts: &options{}}
 	for _, opt := range opts {
 		opt(s)
 	}
 
-	if s.opts.devicesRoot == nil {
+	defaults := []struct {
+		root **os.Root
+		path string
+	}{
 		// ref: https://www.kernel.org/doc/Documentation/filesystems/sysfs.txt
-		r, err := os.OpenRoot("/sys/devices")
-		if err != nil {
-			return nil, err
+		{root: &s.opts.devicesRoot, path: "/sys/devices"},
+		{root: &s.opts.udevDataRoot, path: "/run/udev/data"},
+	}
+	for _, d := range defaults {
+		if *d.root != nil {
+			continue
 		}
 
-		s.opts.devicesRoot = r
-	}
-	if s.opts.udevDataRoot == nil {
-		r, err := os.OpenRoot("/run/udev/data")
+		r, err := os.OpenRoot(d.path)
 		if err != nil {
+			_ = s.Close()
 			return nil, err
 		}
-		s.opts.udevDataRoot = r
+
+		*d.root = r
+		s.owned = append(s.owned, r)
 	}
 
 	return s, nil
 }
 
+// Close releases the roots opened by the scanner, leaving alone those
+// provided with WithDevicesRoot, WithUDevDataRoot and WithClassRoot, which
+// are owned by the caller. The scanner must not be used after Close.
+func (s *scanner) Close() error {
+	s.rootMu.Lock()
+	defer s.rootMu.Unlock()
+
+	var errs []error
+	for _, r := range s.owned {
+		errs = append(errs, r.Close())
+	}
+	s.owned = nil
+
+	return errors.Join(errs...)
+}
+
 // ScanDevices scans directories for `uevent` files and creates a device tree.
+// The devices, and the Children of each device, are sorted by Devpath.
+//
+// The returned devices are not modified by the scanner once returned, so
+// they can be read concurrently. Use FreezeTree to take a copy that is
+// isolated from any later changes made by the caller.
 func (s *scanner) ScanDevices() ([]*types.Device, error) {
-	devices := []*types.Device{}
-	devicesMap := map[string]*types.Device{}
+	return s.ScanDevicesContext(context.Background())
+}
 
-	err := fs.WalkDir(s.opts.devicesRoot.FS(), ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
+// ScanDevicesContext is like ScanDevices, but aborts the scan returning
+// ctx.Err() when ctx is cancelled or its deadline passes. The context is
+// checked for each directory walked.
+func (s *scanner) ScanDevicesContext(ctx context.Context) ([]*types.Device, error) {
+	var devices []*types.Device
+	var err error
+	if s.matchDuringWalk() {
+		devices, err = s.scanIncremental(ctx)
+	} else {
+		devices, err = s.scan(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		if s.opts.pathFilterPattern != nil {
-			if !s.opts.pathFilterPattern.MatchString(path) {
-				return nil
-			}
-		}
+	if s.opts.warnOnEmptyFilter && s.opts.pathFilterPattern != nil && len(devices) == 0 {
+		s.opts.log().Warn("path filter pattern matched no devices", "pattern", s.opts.pathFilterPattern.String())
+	}
 
-		if d.IsDir() || d.Name() != "uevent" {
-			return nil
-		}
+	if len(s.opts.subsystems) > 0 {
+		devices = slices.DeleteFunc(devices, func(d *types.Device) bool {
+			return !s.hasSubsystem(d)
+		})
+	}
+
+	if s.opts.matcher != nil {
+		devices = s.opts.matcher.Matches(devices)
+	}
+
+	if s.opts.maxDevices > 0 && len(devices) > s.opts.maxDevices {
+		devices = devices[:s.opts.maxDevices]
+	}
+
+	if s.opts.syntheticRoot && len(devices) > 0 {
+		devices = addSyntheticRoot(devices)
+	}
+
+	return devices, nil
+}
+
+// scan walks the devices root and returns all devices found, linked
+// into a tree, without applying the filters nor the matcher.
+func (s *scanner) scan(ctx context.Context) ([]*types.Device, error) {
+	var paths []string
+	err := s.walk(ctx, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	devicesMap, err := s.parseDevices(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return linkTree(devicesMap), nil
+}
 
+// matchDuringWalk reports whether the matcher can be evaluated while
+// walking, so that the walk stops as soon as enough devices are found.
+// That is only the case when the number of devices is bounded and none
+// of the rules depend on the device tree.
+func (s *scanner) matchDuringWalk() bool {
+	return s.opts.maxDevices > 0 && s.opts.matcher != nil && !s.opts.matcher.RequiresTree()
+}
+
+// scanIncremental parses devices serially while walking, stopping the
+// walk once WithMaxDevices matching devices are found. Only the devices
+// visited up to that point are linked into the tree.
+func (s *scanner) scanIncremental(ctx context.Context) ([]*types.Device, error) {
+	devicesMap := map[string]*types.Device{}
+	matches := 0
+
+	err := s.walk(ctx, func(path string) error {
 		device, err := s.getDevice(path)
 		if err != nil {
-			slog.Debug("failed to get device", "path", path, "error", err)
+			s.handleError(path, err)
 			return nil
 		}
 
@@ -89,14 +201,69 @@ func (s *scanner) ScanDevices() ([]*types.Device, error) {
 		}
 
 		devicesMap[device.Devpath] = device
+		if s.hasSubsystem(device) && s.opts.matcher.Match(device) {
+			matches++
+		}
+
+		if matches >= s.opts.maxDevices {
+			return fs.SkipAll
+		}
+
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	return linkTree(devicesMap), nil
+}
+
+// handleError handles an error found while scanning path, which does not
+// abort the scan.
+func (s *scanner) handleError(path string, err error) {
+	s.opts.log().Debug("failed to scan path", "path", path, "error", err)
+	if s.opts.errorHandler == nil {
+		return
+	}
+
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	s.opts.errorHandler(path, err)
+}
+
+// hasSubsystem reports whether d is allowed by WithSubsystemFilter.
+func (s *scanner) hasSubsystem(d *types.Device) bool {
+	return len(s.opts.subsystems) == 0 || slices.Contains(s.opts.subsystems, d.Subsystem)
+}
+
+// addSyntheticRoot links the roots of devices as the Children of a new
+// Synthetic device with an empty Devpath, which becomes the only root.
+// It is prepended to devices, as it sorts first by Devpath.
+func addSyntheticRoot(devices []*types.Device) []*types.Device {
+	root := &types.Device{
+		Synthetic: true,
+		Env:       map[string]string{},
+		Attrs:     map[string]string{},
+	}
+
+	for _, d := range Roots(devices) {
+		d.Parent = root
+		root.Children = append(root.Children, d)
+	}
+
+	return append([]*types.Device{root}, devices...)
+}
+
+// linkTree links the devices into a tree, based on their Devpath, and
+// returns them sorted by Devpath. As parents sort before their children,
+// the Children of each device are sorted too, and the IDs inherited from
+// ancestors are already set when linking their children.
+func linkTree(devicesMap map[string]*types.Device) []*types.Device {
+	devices := make([]*types.Device, 0, len(devicesMap))
+
 	// make tree
-	for _, v := range devicesMap {
+	for _, key := range slices.Sorted(maps.Keys(devicesMap)) {
+		v := devicesMap[key]
 		parts := strings.Split(v.Devpath, "/")
 
 		devpath := v.Devpath
@@ -123,11 +290,155 @@ func (s *scanner) ScanDevices() ([]*types.Device, error) {
 		devices = append(devices, v)
 	}
 
-	if s.opts.matcher != nil {
-		return s.opts.matcher.Matches(devices), nil
+	return devices
+}
+
+// walk calls fn with the path of each `uevent` file found in the
+// devices root, stopping if fn returns an error or fs.SkipAll.
+func (s *scanner) walk(ctx context.Context, fn func(path string) error) error {
+	return fs.WalkDir(s.opts.devicesRoot.FS(), ".", func(path string, d fs.DirEntry, err error) error {
+		if d != nil && d.IsDir() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if err != nil {
+			s.handleError(path, err)
+			return nil
+		}
+
+		if d.IsDir() && s.opts.maxDepth > 0 && path != "." {
+			if strings.Count(path, "/")+1 > s.opts.maxDepth {
+				return fs.SkipDir
+			}
+		}
+
+		if d.IsDir() || d.Name() != "uevent" {
+			return nil
+		}
+
+		// The pattern is only matched against the device paths, so that
+		// the walk still descends into non-matching directories that may
+		// contain matching devices.
+		if s.opts.pathFilterPattern != nil {
+			if !s.opts.pathFilterPattern.MatchString(filepath.Dir(path)) {
+				return nil
+			}
+		}
+
+		return fn(path)
+	})
+}
+
+// parseDevices reads the devices of the given `uevent` paths, using a
+// bounded pool of workers. The devices are returned keyed by Devpath.
+func (s *scanner) parseDevices(ctx context.Context, paths []string) (map[string]*types.Device, error) {
+	var mu sync.Mutex
+	devicesMap := map[string]*types.Device{}
+	ch := make(chan string)
+
+	wait := s.startParsers(ch, func(device *types.Device) {
+		mu.Lock()
+		devicesMap[device.Devpath] = device
+		mu.Unlock()
+	})
+
+	var err error
+	for _, path := range paths {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		ch <- path
+	}
+	close(ch)
+	wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return devicesMap, nil
+}
+
+// startParsers starts a bounded pool of workers reading the devices of
+// the `uevent` paths received from paths, calling fn concurrently with
+// each device read. The returned function waits for the workers, which
+// return once paths is closed.
+func (s *scanner) startParsers(paths <-chan string, fn func(device *types.Device)) (wait func()) {
+	workers := s.opts.concurrency
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for path := range paths {
+				device, err := s.getDevice(path)
+				if err != nil {
+					s.handleError(path, err)
+					continue
+				}
+
+				if device == nil {
+					continue
+				}
+
+				fn(device)
+			}
+		}()
+	}
+
+	return wg.Wait
+}
+
+// GetDevice reads a single device, without walking the devices tree.
+// The syspath can be either absolute (e.g. `/sys/devices/platform/...`)
+// or relative to the devices root, and must be within the devices root.
+//
+// The device Parent and Children are not resolved. If syspath has no
+// `uevent` file, the returned error wraps ErrNotDevice.
+func (s *scanner) GetDevice(syspath string) (*types.Device, error) {
+	path, err := s.devicePath(syspath)
+	if err != nil {
+		return nil, err
+	}
+
+	uevent := filepath.Join(path, "uevent")
+	_, err = s.opts.devicesRoot.Stat(uevent)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%q: %w", syspath, ErrNotDevice)
+		}
+
+		return nil, err
+	}
+
+	return s.getDevice(uevent)
+}
+
+// devicePath returns syspath relative to the devices root, erroring if
+// syspath is not within it.
+func (s *scanner) devicePath(syspath string) (string, error) {
+	path := filepath.Clean(syspath)
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(s.opts.devicesRoot.Name(), path)
+		if err != nil {
+			return "", fmt.Errorf("path %q is not within the devices root: %w", syspath, err)
+		}
+		path = rel
+	}
+
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("path %q is not within the devices root", syspath)
 	}
 
-	return devices, err
+	return path, nil
 }
 
 func (s *scanner) getDevice(path string) (*types.Device, error) {
@@ -142,6 +453,7 @@ func (s *scanner) getDevice(path string) (*types.Device, error) {
 		Attrs:   attrs,
 		Parent:  nil,
 	}
+	setSysname(device)
 
 	if id, ok := s.readId(filepath.Join(filepath.Dir(path), "idVendor")); ok {
 		device.VendorID = id
@@ -154,10 +466,63 @@ func (s *scanner) getDevice(path string) (*types.Device, error) {
 	if err != nil {
 		return nil, err
 	}
+	addLinks(device, strings.Fields(device.Env["DEVLINKS"])...)
+
+	// The subsystem symlink is authoritative, but is not always
+	// available (e.g. mocked trees), in which case fallback to the
+	// uevent data.
+	if subsystem, ok := s.readLinkBase(filepath.Join(filepath.Dir(path), "subsystem")); ok {
+		device.Subsystem = subsystem
+	} else {
+		device.Subsystem = device.Env["SUBSYSTEM"]
+	}
+	// Same for the driver, which is left empty for unbound devices.
+	if driver, ok := s.readLinkBase(filepath.Join(filepath.Dir(path), "driver")); ok {
+		device.Driver = driver
+	} else {
+		device.Driver = device.Env["DRIVER"]
+	}
+
+	if s.opts.parseDescriptors {
+		s.readDescriptors(device)
+	}
 
 	return device, nil
 }
 
+// readDescriptors decodes the `descriptors` file of USB devices into
+// the device Descriptors. Devices without the file are silently skipped,
+// while malformed files, and those larger than WithMaxAttrSize, are
+// reported to the error handler.
+func (s *scanner) readDescriptors(device *types.Device) {
+	path := filepath.Join(device.Devpath, "descriptors")
+	b, err := s.readLimited(path)
+	if err != nil {
+		// Truncated files are already reported, and cannot be decoded.
+		if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrAttrTruncated) {
+			s.handleError(path, err)
+		}
+		return
+	}
+
+	descs, err := types.ParseUSBDescriptors(b)
+	if err != nil {
+		s.handleError(path, err)
+		return
+	}
+	device.Descriptors = descs
+}
+
+// readLinkBase returns the basename of the target of the symlink at path.
+func (s *scanner) readLinkBase(path string) (string, bool) {
+	target, err := s.opts.devicesRoot.Readlink(path)
+	if err != nil {
+		return "", false
+	}
+
+	return filepath.Base(target), true
+}
+
 func (s *scanner) readId(path string) (string, bool) {
 	_, err := s.opts.devicesRoot.Stat(path)
 	if err != nil {
@@ -171,7 +536,7 @@ func (s *scanner) readId(path string) (string, bool) {
 
 	defer func() {
 		if err := f.Close(); err != nil {
-			slog.Debug("cannot close ID file", "error", err)
+			s.opts.log().Debug("cannot close ID file", "error", err)
 		}
 	}()
 
@@ -182,29 +547,170 @@ func (s *scanner) readId(path string) (string, bool) {
 	return strings.Trim(string(d), "\n\r\t "), true
 }
 
-func (s *scanner) readAttrs(path string) (map[string]string, error) {
-	attrs := map[string]string{}
+// attrNames returns the names of the attrs to read from the device dir
+// at path. With an allowlist, only the allowed attrs are looked up,
+// without listing the dir.
+func (s *scanner) attrNames(path string) ([]string, error) {
+	if len(s.opts.attrAllowlist) > 0 {
+		return s.regularAttrs(path, s.opts.attrAllowlist), nil
+	}
+
 	files, err := fs.ReadDir(s.opts.devicesRoot.FS(), path)
 	if err != nil {
-		return attrs, err
+		return nil, err
 	}
 
+	var names []string
 	for _, f := range files {
-		if f.IsDir() || f.Name() == "uevent" || f.Name() == "descriptors" {
+		if f.Type().IsRegular() && f.Name() != "uevent" && f.Name() != "descriptors" {
+			names = append(names, f.Name())
+		}
+	}
+
+	return append(names, s.regularAttrs(path, nestedAttrs)...), nil
+}
+
+// regularAttrs returns the names that are regular files within the
+// device dir at path, leaving out `uevent` and `descriptors`.
+func (s *scanner) regularAttrs(path string, names []string) []string {
+	var found []string
+	for _, name := range names {
+		if name == "uevent" || name == "descriptors" {
 			continue
 		}
 
-		data, err := fs.ReadFile(s.opts.devicesRoot.FS(), filepath.Join(path, f.Name()))
-		if err != nil {
+		info, err := s.opts.devicesRoot.Lstat(filepath.Join(path, name))
+		if err == nil && info.Mode().IsRegular() {
+			found = append(found, name)
+		}
+	}
+
+	return found
+}
+
+// nestedAttrs lists the attrs that are read from the subdirectories of
+// the devices, which are stored in Attrs under their relative path (e.g.
+// `power/wakeup`).
+var nestedAttrs = []string{
+	"power/wakeup",
+	"queue/logical_block_size",
+	"queue/physical_block_size",
+}
+
+func (s *scanner) readAttrs(path string) (map[string]string, error) {
+	attrs := map[string]string{}
+	names, err := s.attrNames(path)
+	if err != nil {
+		return attrs, err
+	}
+
+	if s.opts.maxAttrsPerDevice > 0 && len(names) > s.opts.maxAttrsPerDevice {
+		s.opts.log().Debug("device attrs truncated", "path", path, "max", s.opts.maxAttrsPerDevice)
+		names = names[:s.opts.maxAttrsPerDevice]
+	}
+
+	if s.opts.attrConcurrency > 1 {
+		attrs = s.readAttrsConcurrently(path, names)
+	} else {
+		for _, name := range names {
+			if v, ok := s.readAttr(filepath.Join(path, name)); ok {
+				attrs[name] = v
+			}
+		}
+	}
+
+	// Only symlinks are followed, as regular files are already read.
+	for _, name := range s.opts.symlinkAttrs {
+		info, err := s.opts.devicesRoot.Lstat(filepath.Join(path, name))
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
 			continue
 		}
 
-		attrs[f.Name()] = strings.Trim(string(data), "\n\r\t ")
+		if v, ok := s.readLinkBase(filepath.Join(path, name)); ok {
+			attrs[name] = v
+		}
 	}
 
 	return attrs, nil
 }
 
+// readAttrsConcurrently reads the attrs of the device at path, using a
+// bounded pool of workers.
+func (s *scanner) readAttrsConcurrently(path string, names []string) map[string]string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	attrs := map[string]string{}
+	ch := make(chan string)
+
+	for range min(s.opts.attrConcurrency, len(names)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for name := range ch {
+				v, ok := s.readAttr(filepath.Join(path, name))
+				if !ok {
+					continue
+				}
+
+				mu.Lock()
+				attrs[name] = v
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		ch <- name
+	}
+	close(ch)
+	wg.Wait()
+
+	return attrs
+}
+
+func (s *scanner) readAttr(path string) (string, bool) {
+	data, err := s.readLimited(path)
+	if err != nil && !errors.Is(err, ErrAttrTruncated) {
+		return "", false
+	}
+
+	return strings.Trim(string(data), "\n\r\t "), true
+}
+
+// readLimited reads the file at path, up to the size set with
+// WithMaxAttrSize. Larger files are truncated to that size, and reported
+// to the error handler, in which case the returned error wraps
+// ErrAttrTruncated along with the data read.
+func (s *scanner) readLimited(path string) ([]byte, error) {
+	f, err := s.opts.devicesRoot.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			s.opts.log().Debug("cannot close attr file", "error", err)
+		}
+	}()
+
+	// Read one extra byte to tell a truncated file from one of exactly
+	// the limit size.
+	limit := s.opts.attrSize()
+	data, err := io.ReadAll(io.LimitReader(f, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > limit {
+		err := fmt.Errorf("%w: larger than %d bytes", ErrAttrTruncated, limit)
+		s.handleError(path, err)
+		return data[:limit], err
+	}
+
+	return data, nil
+}
+
 func (s *scanner) readUeventFile(path string, device *types.Device) error {
 	_, err := s.opts.devicesRoot.Stat(path)
 	if err != nil {
@@ -222,7 +728,7 @@ func (s *scanner) readUeventFile(path string, device *types.Device) error {
 
 	defer func() {
 		if err := f.Close(); err != nil {
-			slog.Debug("cannot close uevent file", "error", err)
+			s.opts.log().Debug("cannot close uevent file", "error", err)
 		}
 	}()
 
@@ -233,7 +739,7 @@ func (s *scanner) readUeventFile(path string, device *types.Device) error {
 			continue
 		}
 
-		device.Env[k] = v
+		setEnv(device.Env, k, v)
 	}
 	err = buf.Err()
 	if err != nil {
@@ -271,7 +777,7 @@ func (s *scanner) readDevFile(path string) (string, error) {
 
 	defer func() {
 		if err := f.Close(); err != nil {
-			slog.Debug("cannot close dev file", "error", err)
+			s.opts.log().Debug("cannot close dev file", "error", err)
 		}
 	}()
 
@@ -298,7 +804,7 @@ func (s *scanner) readUdevInfo(devString string, d *types.Device) error {
 
 	defer func() {
 		if err := f.Close(); err != nil {
-			slog.Debug("cannot close udev info file", "error", err)
+			s.opts.log().Debug("cannot close udev info file", "error", err)
 		}
 	}()
 
@@ -319,13 +825,18 @@ func (s *scanner) readUdevInfo(devString string, d *types.Device) error {
 			continue
 		}
 
+		if k == "S" {
+			addLinks(d, v)
+			continue
+		}
+
 		if k == "E" {
 			ck, cv, ok := strings.Cut(v, "=")
 			if !ok {
 				continue
 			}
 
-			d.Env[ck] = cv
+			setEnv(d.Env, ck, cv)
 		}
 	}
 
@@ -336,3 +847,63 @@ func (s *scanner) readUdevInfo(devString string, d *types.Device) error {
 
 	return nil
 }
+
+// setEnv sets the key of env to v. Keys holding lists, which may be
+// repeated (e.g. in both the uevent file and the udev data), accumulate
+// their values without duplicates rather than being overwritten: the
+// space separated `DEVLINKS`, and the `:` separated `TAGS` and
+// `CURRENT_TAGS`. Other keys are overwritten by the last value.
+func setEnv(env map[string]string, k, v string) {
+	switch k {
+	case "DEVLINKS":
+		env[k] = strings.Join(mergeFields(env[k], v, strings.Fields), " ")
+	case "TAGS", "CURRENT_TAGS":
+		split := func(s string) []string { return strings.Split(s, ":") }
+		if tags := mergeFields(env[k], v, split); len(tags) > 0 {
+			env[k] = ":" + strings.Join(tags, ":") + ":"
+		} else {
+			env[k] = ""
+		}
+	default:
+		env[k] = v
+	}
+}
+
+// mergeFields returns the non empty fields of old followed by the ones of
+// v that are not already present.
+func mergeFields(old, v string, split func(string) []string) []string {
+	var fields []string
+	for _, f := range append(split(old), split(v)...) {
+		if f != "" && !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// addLinks adds the device node symlinks to d, normalising them to be
+// relative to /dev and skipping duplicates.
+func addLinks(d *types.Device, links ...string) {
+	for _, l := range links {
+		l = strings.TrimPrefix(l, "/dev/")
+		if l == "" || slices.Contains(d.Links, l) {
+			continue
+		}
+
+		d.Links = append(d.Links, l)
+	}
+}
+
+// setSysname sets the sysname and sysnum of d, based on its Devpath.
+// As done by udev, `!` in the sysname is replaced by `/`, and sysnum
+// holds the trailing digits of the sysname, if any.
+func setSysname(d *types.Device) {
+	d.Sysname = strings.ReplaceAll(filepath.Base(d.Devpath), "!", "/")
+
+	i := len(d.Sysname)
+	for i > 0 && d.Sysname[i-1] >= '0' && d.Sysname[i-1] <= '9' {
+		i--
+	}
+	d.Sysnum = d.Sysname[i:]
+}
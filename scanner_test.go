@@ -2,12 +2,25 @@ package libudev
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/qubesome/libudev/matcher"
+	"github.com/qubesome/libudev/types"
 )
 
 func TestNewScanner(t *testing.T) {
@@ -18,6 +31,59 @@ func TestNewScanner(t *testing.T) {
 	}
 }
 
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	devRoot, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	udevDataRoot, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(p string) { defaultClassPath = p }(defaultClassPath)
+	defaultClassPath = filepath.Join(dir, "no-class")
+
+	s, err := NewScanner(WithDevicesRoot(devRoot), WithUDevDataRoot(udevDataRoot))
+	if err != nil {
+		t.Fatal("failed to create scanner without a class dir", err)
+	}
+	if s.opts.classRoot != nil {
+		t.Fatal("want the class root opened lazily")
+	}
+	if _, err := s.ScanClass("net"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want ErrNotExist scanning a missing class dir got %v", err)
+	}
+
+	defaultClassPath = filepath.Join(dir, "class")
+	if err := os.MkdirAll(filepath.Join(defaultClassPath, "net"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ScanClass("net"); err != nil {
+		t.Fatal("failed to scan class", err)
+	}
+	classRoot := s.opts.classRoot
+
+	if err := s.Close(); err != nil {
+		t.Fatal("failed to close scanner", err)
+	}
+
+	if _, err := classRoot.Stat("."); err == nil {
+		t.Error("want the default class root closed")
+	}
+
+	for _, r := range []*os.Root{devRoot, udevDataRoot} {
+		if _, err := r.Stat("."); err != nil {
+			t.Errorf("want caller provided root %q open got %v", r.Name(), err)
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Error("want Close to be idempotent", err)
+	}
+}
+
 func TestScanDevices(t *testing.T) {
 	dir := t.TempDir()
 	err := unzip("./assets/fixtures/demo_tree.zip", dir)
@@ -74,6 +140,36 @@ func TestScanDevices(t *testing.T) {
 	}
 }
 
+func TestScanDevicesOrder(t *testing.T) {
+	byDevpath := func(a, b *types.Device) int {
+		return strings.Compare(a.Devpath, b.Devpath)
+	}
+
+	var first []string
+	for range 5 {
+		devices, err := newDemoScanner(t).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+
+		if !slices.IsSortedFunc(devices, byDevpath) {
+			t.Fatal("want devices sorted by Devpath")
+		}
+		for _, d := range devices {
+			if !slices.IsSortedFunc(d.Children, byDevpath) {
+				t.Fatalf("want children of %q sorted by Devpath", d.Devpath)
+			}
+		}
+
+		got := devpaths(devices)
+		if first == nil {
+			first = got
+		} else if !slices.Equal(first, got) {
+			t.Fatalf("want the same order on every scan, got %v and %v", first, got)
+		}
+	}
+}
+
 func TestScanDevicesNotFound(t *testing.T) {
 	devRoot, err := os.OpenRoot(t.TempDir())
 	if err != nil {
@@ -218,3 +314,1316 @@ func TestScanDevicesWithMatcher(t *testing.T) {
 		}
 	}
 }
+
+// newDemoScanner returns a scanner pointing to the extracted demo tree.
+func newDemoScanner(t *testing.T, opts ...Option) *scanner {
+	t.Helper()
+
+	dir := t.TempDir()
+	err := unzip("./assets/fixtures/demo_tree.zip", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devRoot, err := os.OpenRoot(filepath.Join(dir, "demo_tree/sys/devices"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	udevDataRoot, err := os.OpenRoot(filepath.Join(dir, "demo_tree/run/udev/data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The demo tree has no class dir.
+	classDir := filepath.Join(dir, "demo_tree/sys/class")
+	if err := os.MkdirAll(classDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	classRoot, err := os.OpenRoot(classDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithDevicesRoot(devRoot), WithUDevDataRoot(udevDataRoot), WithClassRoot(classRoot)}, opts...)
+	s, err := NewScanner(opts...)
+	if err != nil {
+		t.Fatal("failed to create scanner", err)
+	}
+
+	return s
+}
+
+// fixture describes a sysfs-like tree created on the fly for tests.
+type fixture struct {
+	// devices maps paths relative to the devices root to their contents.
+	devices map[string]string
+	// links maps symlink paths relative to the devices root to their
+	// targets.
+	links map[string]string
+	// udev maps paths relative to the udev data root to their contents.
+	udev map[string]string
+	// class maps symlink paths relative to the class root to their
+	// targets.
+	class map[string]string
+}
+
+// newFixtureScanner writes f into a temporary dir and returns a scanner
+// using it as devices and udev data roots.
+func newFixtureScanner(t *testing.T, f fixture, opts ...Option) *scanner {
+	t.Helper()
+
+	dir := t.TempDir()
+	devDir := filepath.Join(dir, "sys/devices")
+	udevDir := filepath.Join(dir, "run/udev/data")
+	classDir := filepath.Join(dir, "sys/class")
+
+	writeFiles(t, devDir, f.devices)
+	writeFiles(t, udevDir, f.udev)
+	writeFiles(t, classDir, nil)
+	writeLinks(t, devDir, f.links)
+	writeLinks(t, classDir, f.class)
+
+	devRoot, err := os.OpenRoot(devDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	udevDataRoot, err := os.OpenRoot(udevDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	classRoot, err := os.OpenRoot(classDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithDevicesRoot(devRoot), WithUDevDataRoot(udevDataRoot), WithClassRoot(classRoot)}, opts...)
+	s, err := NewScanner(opts...)
+	if err != nil {
+		t.Fatal("failed to create scanner", err)
+	}
+
+	return s
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeLinks creates the symlinks under root, mapping their paths to
+// their targets.
+func writeLinks(t *testing.T, root string, links map[string]string) {
+	t.Helper()
+
+	for link, target := range links {
+		path := filepath.Join(root, link)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanDevicesWithPathFilterPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    int
+	}{
+		{pattern: `usbmisc/lp0$`, want: 1},
+		{pattern: `/2-1\.2(/|$)`, want: 4},
+		{pattern: `^platform/`, want: 1},
+		{pattern: `^no-match$`, want: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.pattern, func(t *testing.T) {
+			s := newDemoScanner(t, WithPathFilterPattern(regexp.MustCompile(tc.pattern)))
+
+			devices, err := s.ScanDevices()
+			if err != nil {
+				t.Fatal("failed to scan the demo tree", err)
+			}
+
+			if len(devices) != tc.want {
+				t.Fatalf("wanted %d devices got %d", tc.want, len(devices))
+			}
+		})
+	}
+}
+
+func TestScanDevicesWithMaxAttrsPerDevice(t *testing.T) {
+	files := map[string]string{
+		"virtual/misc/foo/uevent": "DEVNAME=foo\n",
+	}
+	for i := range 50 {
+		files[fmt.Sprintf("virtual/misc/foo/attr%02d", i)] = "value\n"
+	}
+
+	tests := []struct {
+		max  int
+		want int
+	}{
+		{max: 0, want: 50},
+		{max: 5, want: 5},
+		{max: 100, want: 50},
+	}
+
+	for _, tc := range tests {
+		s := newFixtureScanner(t, fixture{devices: files}, WithMaxAttrsPerDevice(tc.max))
+
+		devices, err := s.ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		if len(devices) != 1 {
+			t.Fatalf("wanted 1 device got %d", len(devices))
+		}
+
+		if len(devices[0].Attrs) != tc.want {
+			t.Errorf("max %d: wanted %d attrs got %d", tc.max, tc.want, len(devices[0].Attrs))
+		}
+	}
+}
+
+func TestScanDevicesWithMaxAttrSize(t *testing.T) {
+	files := map[string]string{
+		"virtual/misc/foo/uevent": "DEVNAME=foo\n",
+		"virtual/misc/foo/small":  "abc\n",
+		"virtual/misc/foo/exact":  "12345678",
+		"virtual/misc/foo/large":  strings.Repeat("x", 20),
+		"virtual/misc/foo/huge":   strings.Repeat("y", maxDevSize+1),
+	}
+
+	tests := []struct {
+		max       int
+		want      map[string]int
+		truncated []string
+	}{
+		{
+			max:       0,
+			want:      map[string]int{"small": 3, "exact": 8, "large": 20, "huge": maxDevSize},
+			truncated: []string{"virtual/misc/foo/huge"},
+		},
+		{
+			max:       8,
+			want:      map[string]int{"small": 3, "exact": 8, "large": 8, "huge": 8},
+			truncated: []string{"virtual/misc/foo/huge", "virtual/misc/foo/large"},
+		},
+	}
+
+	for _, tc := range tests {
+		var truncated []string
+		s := newFixtureScanner(t, fixture{devices: files}, WithMaxAttrSize(tc.max),
+			WithErrorHandler(func(path string, err error) {
+				if errors.Is(err, ErrAttrTruncated) {
+					truncated = append(truncated, path)
+				}
+			}))
+
+		devices, err := s.ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		if len(devices) != 1 {
+			t.Fatalf("wanted 1 device got %d", len(devices))
+		}
+
+		for name, n := range tc.want {
+			if len(devices[0].Attrs[name]) != n {
+				t.Errorf("max %d: wanted %s of %d bytes got %d", tc.max, name, n, len(devices[0].Attrs[name]))
+			}
+		}
+
+		slices.Sort(truncated)
+		if !slices.Equal(truncated, tc.truncated) {
+			t.Errorf("max %d: want truncated %v got %v", tc.max, tc.truncated, truncated)
+		}
+	}
+}
+
+func TestScanDevicesWithMaxAttrSizeDescriptors(t *testing.T) {
+	mouse := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"
+	descriptors := string([]byte{
+		0x12, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x08, 0x6d, 0x04, 0x5b, 0xc0, 0x00, 0x54, 0x01, 0x02, 0x00, 0x01,
+		0x09, 0x02, 0x22, 0x00, 0x01, 0x01, 0x00, 0xa0, 0x31,
+	})
+	f := fixture{
+		devices: map[string]string{
+			mouse + "/uevent":      "DEVTYPE=usb_device\n",
+			mouse + "/descriptors": descriptors,
+		},
+	}
+
+	var errs []error
+	s := newFixtureScanner(t, f, WithParseDescriptors(), WithMaxAttrSize(20), WithErrorHandler(func(path string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the tree", err)
+	}
+
+	if d := findDevice(t, devices, mouse); d.Descriptors != nil {
+		t.Errorf("want oversize descriptors left undecoded got %v", d.Descriptors)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrAttrTruncated) {
+		t.Errorf("want a single ErrAttrTruncated got %v", errs)
+	}
+}
+
+func TestScanDevicesWithAttrAllowlist(t *testing.T) {
+	mouse := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"
+
+	s := newDemoScanner(t, WithAttrAllowlist("idVendor", "idProduct", "dev", "address", "uevent"))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	d := findDevice(t, devices, mouse)
+	want := map[string]string{"idVendor": "046d", "idProduct": "c05b", "dev": "189:131"}
+	if !reflect.DeepEqual(d.Attrs, want) {
+		t.Errorf("want attrs %v got %v", want, d.Attrs)
+	}
+	if d.Env["DEVTYPE"] != "usb_device" {
+		t.Errorf("want env read from uevent got %v", d.Env)
+	}
+
+	s = newDemoScanner(t, WithAttrAllowlist())
+	devices, err = s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if d := findDevice(t, devices, mouse); len(d.Attrs) <= len(want) || d.Attrs["speed"] != "1.5" {
+		t.Errorf("want all attrs read with an empty allowlist got %v", d.Attrs)
+	}
+}
+
+func TestScanDevicesWithFollowSymlinkAttrs(t *testing.T) {
+	intf := "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0"
+	f := fixture{
+		devices: map[string]string{
+			intf + "/uevent":          "DEVTYPE=usb_interface\n",
+			intf + "/bInterfaceClass": "03\n",
+			intf + "/port/uevent":     "",
+		},
+		links: map[string]string{
+			intf + "/subsystem":     "../../../../../../bus/usb",
+			intf + "/driver":        "../../../../../../bus/usb/drivers/usbhid",
+			intf + "/firmware_node": "../../../../../../firmware/acpi/PNP0A08:00",
+		},
+	}
+
+	for _, opts := range [][]Option{nil, {WithAttrConcurrency(4)}} {
+		opts = append(opts, WithFollowSymlinkAttrs("driver", "subsystem", "bInterfaceClass", "port", "missing"))
+		devices, err := newFixtureScanner(t, f, opts...).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		d := findDevice(t, devices, intf)
+		want := map[string]string{"bInterfaceClass": "03", "driver": "usbhid", "subsystem": "usb"}
+		if !reflect.DeepEqual(d.Attrs, want) {
+			t.Errorf("want attrs %v got %v", want, d.Attrs)
+		}
+	}
+}
+
+func TestScanDevicesContext(t *testing.T) {
+	s := newDemoScanner(t)
+
+	devices, err := s.ScanDevicesContext(context.Background())
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 11 {
+		t.Fatalf("wanted 11 devices got %d", len(devices))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	devices, err = s.ScanDevicesContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled got %v", err)
+	}
+
+	if devices != nil {
+		t.Fatalf("want no devices got %d", len(devices))
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, err = s.ScanDevicesContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded got %v", err)
+	}
+}
+
+func TestScanDevicesWithConcurrency(t *testing.T) {
+	tree := func(devices []*types.Device) map[string]string {
+		m := map[string]string{}
+		for _, d := range devices {
+			parent := ""
+			if d.Parent != nil {
+				parent = d.Parent.Devpath
+			}
+			m[d.Devpath] = fmt.Sprintf("%s %d %d %d %s %s", parent, len(d.Children),
+				len(d.Env), len(d.Attrs), d.VendorID, d.ProductID)
+		}
+		return m
+	}
+
+	serial, err := newDemoScanner(t, WithConcurrency(1)).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	for _, n := range []int{0, 2, 16} {
+		parallel, err := newDemoScanner(t, WithConcurrency(n)).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+
+		if len(parallel) != len(serial) {
+			t.Fatalf("concurrency %d: wanted %d devices got %d", n, len(serial), len(parallel))
+		}
+
+		if !reflect.DeepEqual(tree(serial), tree(parallel)) {
+			t.Errorf("concurrency %d: want tree %v got %v", n, tree(serial), tree(parallel))
+		}
+	}
+}
+
+func TestScanInputDevice(t *testing.T) {
+	input := "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0/0003:046D:C52B.0001/input/input5"
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			input + "/uevent": "PRODUCT=3/46d/c52b/111\nNAME=\"Logitech USB Receiver\"\n",
+			input + "/name":   "Logitech USB Receiver\n",
+			input + "/phys":   "usb-0000:00:14.0-1/input0\n",
+			input + "/uniq":   "4a-6b-2c-01\n",
+		},
+		links: map[string]string{
+			input + "/subsystem": "../../../../../../../../class/input",
+		},
+	})
+
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+
+	d := devices[0]
+	if d.Subsystem != "input" {
+		t.Errorf("want Subsystem %q got %q", "input", d.Subsystem)
+	}
+	if d.InputPhys() != "usb-0000:00:14.0-1/input0" {
+		t.Errorf("want phys %q got %q", "usb-0000:00:14.0-1/input0", d.InputPhys())
+	}
+	if d.InputUniq() != "4a-6b-2c-01" {
+		t.Errorf("want uniq %q got %q", "4a-6b-2c-01", d.InputUniq())
+	}
+}
+
+func TestScanDevicesWithMaxDevices(t *testing.T) {
+	s := newDemoScanner(t, WithMaxDevices(3))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 3 {
+		t.Fatalf("wanted 3 devices got %d", len(devices))
+	}
+
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleEnv("DEVNAME", "^input/"))
+
+	s = newDemoScanner(t, WithMatcher(m), WithMaxDevices(1))
+	devices, err = s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+
+	if devices[0].Env["DEVNAME"] != "input/event2" {
+		t.Errorf("want first match %q got %q", "input/event2", devices[0].Env["DEVNAME"])
+	}
+
+	// The walk stops on the first match, so its ancestors (which are
+	// walked after their children) are never visited.
+	if devices[0].Parent != nil {
+		t.Errorf("want walk to stop early, but found parent %q", devices[0].Parent.Devpath)
+	}
+
+	m.AddRule(treeRule{})
+	s = newDemoScanner(t, WithMatcher(m), WithMaxDevices(1))
+	devices, err = s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+
+	if devices[0].Parent == nil {
+		t.Error("want full tree scanned when rules require it")
+	}
+}
+
+// treeRule matches all devices, but requires the device tree.
+type treeRule struct{}
+
+func (treeRule) Match(*types.Device) bool { return true }
+
+func (treeRule) RequiresTree() bool { return true }
+
+func TestScanDevicesWithMaxDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{depth: 0, want: []string{"platform", "platform/serial8250", "platform/serial8250/tty/ttyS0"}},
+		{depth: -1, want: []string{"platform", "platform/serial8250", "platform/serial8250/tty/ttyS0"}},
+		{depth: 1, want: []string{"platform"}},
+		{depth: 3, want: []string{"platform", "platform/serial8250"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(strconv.Itoa(tc.depth), func(t *testing.T) {
+			s := newFixtureScanner(t, fixture{
+				devices: map[string]string{
+					"platform/uevent":                      "",
+					"platform/serial8250/uevent":           "DRIVER=serial8250\n",
+					"platform/serial8250/tty/ttyS0/uevent": "DEVNAME=ttyS0\n",
+				},
+			}, WithMaxDepth(tc.depth))
+
+			devices, err := s.ScanDevices()
+			if err != nil {
+				t.Fatal("failed to scan the tree", err)
+			}
+
+			var got []string
+			for _, d := range devices {
+				got = append(got, d.Devpath)
+			}
+			slices.Sort(got)
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScanDevicesWithParseDescriptors(t *testing.T) {
+	mouse := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"
+	descriptors := string([]byte{
+		0x12, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x08, 0x6d, 0x04, 0x5b, 0xc0, 0x00, 0x54, 0x01, 0x02, 0x00, 0x01,
+		0x09, 0x02, 0x22, 0x00, 0x01, 0x01, 0x00, 0xa0, 0x31,
+	})
+	f := fixture{
+		devices: map[string]string{
+			mouse + "/uevent":         "DEVTYPE=usb_device\n",
+			mouse + "/descriptors":    descriptors,
+			"platform/uevent":         "",
+			"virtual/bad/uevent":      "",
+			"virtual/bad/descriptors": "\x09",
+		},
+	}
+
+	var errs []string
+	s := newFixtureScanner(t, f, WithParseDescriptors(), WithErrorHandler(func(path string, err error) {
+		errs = append(errs, path)
+	}))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the tree", err)
+	}
+
+	d := findDevice(t, devices, mouse)
+	if len(d.Descriptors) != 2 {
+		t.Fatalf("wanted 2 descriptors got %d", len(d.Descriptors))
+	}
+	if dev, ok := d.Descriptors[0].Device(); !ok || dev.VendorID != 0x046d {
+		t.Errorf("want device descriptor with vendor 046d got %+v", dev)
+	}
+
+	if d := findDevice(t, devices, "platform"); d.Descriptors != nil {
+		t.Errorf("want no descriptors got %v", d.Descriptors)
+	}
+
+	if d := findDevice(t, devices, "virtual/bad"); d.Descriptors != nil {
+		t.Errorf("want no descriptors got %v", d.Descriptors)
+	}
+	if !slices.Equal(errs, []string{"virtual/bad/descriptors"}) {
+		t.Errorf("want error for malformed descriptors got %v", errs)
+	}
+
+	s = newFixtureScanner(t, f)
+	devices, err = s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the tree", err)
+	}
+	if d := findDevice(t, devices, mouse); d.Descriptors != nil {
+		t.Errorf("want descriptors unparsed by default got %v", d.Descriptors)
+	}
+}
+
+func TestScanDevicesWithSyntheticRoot(t *testing.T) {
+	s := newDemoScanner(t, WithSyntheticRoot(true))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 12 {
+		t.Fatalf("wanted 12 devices got %d", len(devices))
+	}
+
+	roots := Roots(devices)
+	if len(roots) != 1 {
+		t.Fatalf("wanted a single root got %d", len(roots))
+	}
+
+	root := roots[0]
+	if !root.Synthetic || root.Devpath != "" || root != devices[0] {
+		t.Fatalf("want the synthetic root first got %+v", root)
+	}
+
+	want := []string{"pci0000:00/0000:00:1a.0/usb1", "pci0000:00/0000:00:1d.0/usb2", "platform/serial8250/tty/ttyS17"}
+	if got := devpaths(root.Children); !slices.Equal(got, want) {
+		t.Errorf("want children %v got %v", want, got)
+	}
+
+	if err := ValidateTree(roots); err != nil {
+		t.Error("invalid tree", err)
+	}
+
+	for _, d := range devices[1:] {
+		if d.Synthetic {
+			t.Errorf("device %q marked as synthetic", d.Devpath)
+		}
+	}
+}
+
+func TestScanDevicesWithSyntheticRootFiltered(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleNotEnv("DRIVER", "^usb$"))
+
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{name: "negation matcher", opts: []Option{WithMatcher(m)}, want: 5},
+		{name: "max devices", opts: []Option{WithMaxDevices(2)}, want: 2},
+		{name: "no match", opts: []Option{WithPathFilterPattern(regexp.MustCompile("^no-match$"))}, want: 0},
+	}
+
+	for _, tc := range tests {
+		s := newDemoScanner(t, append(tc.opts, WithSyntheticRoot(true))...)
+		devices, err := s.ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+
+		if tc.want == 0 {
+			if len(devices) != 0 {
+				t.Errorf("%s: wanted no devices got %v", tc.name, devpaths(devices))
+			}
+			continue
+		}
+
+		if len(devices) != tc.want+1 {
+			t.Fatalf("%s: wanted %d devices and the root got %d", tc.name, tc.want, len(devices))
+		}
+
+		root := devices[0]
+		if !root.Synthetic {
+			t.Fatalf("%s: want the synthetic root first got %q", tc.name, root.Devpath)
+		}
+		for _, d := range devices[1:] {
+			if d.Synthetic {
+				t.Errorf("%s: synthetic root returned after the first device", tc.name)
+			}
+		}
+
+		if roots := Roots(devices); len(roots) != 1 || roots[0] != root {
+			t.Errorf("%s: wanted the synthetic root as the only root got %v", tc.name, devpaths(roots))
+		}
+		for _, c := range root.Children {
+			if !slices.Contains(devices[1:], c) {
+				t.Errorf("%s: root child %q was not returned", tc.name, c.Devpath)
+			}
+		}
+	}
+}
+
+func TestGetDevice(t *testing.T) {
+	s := newDemoScanner(t)
+	devpath := "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4"
+
+	for _, syspath := range []string{devpath, filepath.Join(s.opts.devicesRoot.Name(), devpath)} {
+		d, err := s.GetDevice(syspath)
+		if err != nil {
+			t.Fatalf("failed to get device %q: %v", syspath, err)
+		}
+
+		if d.Devpath != devpath {
+			t.Errorf("want Devpath %q got %q", devpath, d.Devpath)
+		}
+		if d.Attrs["dev"] != "189:133" {
+			t.Errorf("want dev %q got %q", "189:133", d.Attrs["dev"])
+		}
+		if d.Env["ID_MODEL"] != "TG2480-H" {
+			t.Errorf("want ID_MODEL %q got %q", "TG2480-H", d.Env["ID_MODEL"])
+		}
+		if d.Parent != nil {
+			t.Error("want Parent not resolved")
+		}
+	}
+
+	for _, syspath := range []string{"pci0000:00", "pci0000:00/not-found"} {
+		_, err := s.GetDevice(syspath)
+		if !errors.Is(err, ErrNotDevice) {
+			t.Errorf("%q: want ErrNotDevice got %v", syspath, err)
+		}
+	}
+
+	for _, syspath := range []string{"../run", "/sys/devices/platform", "/"} {
+		_, err := s.GetDevice(syspath)
+		if err == nil || errors.Is(err, ErrNotDevice) {
+			t.Errorf("%q: want error for path outside devices root got %v", syspath, err)
+		}
+	}
+}
+
+func TestScanDevicesLinks(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleEnv("DEVNAME", "^input/mouse0$"))
+	mouse := m.Matches(devices)
+	if len(mouse) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(mouse))
+	}
+
+	want := []string{
+		"input/by-path/pci-0000:00:1d.0-usb-0:1.2:1.0-mouse",
+		"input/by-id/usb-Logitech_USB_Optical_Mouse-mouse",
+	}
+	if !reflect.DeepEqual(mouse[0].Links, want) {
+		t.Errorf("want Links %v got %v", want, mouse[0].Links)
+	}
+
+	disk := "pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda"
+	s = newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			disk + "/uevent": "MAJOR=8\nMINOR=0\nDEVNAME=sda\nDEVTYPE=disk\n" +
+				"DEVLINKS=/dev/disk/by-id/ata-Samsung_SSD /dev/disk/by-path/pci-0000:00:1f.2-ata-1\n",
+			disk + "/dev": "8:0\n",
+		},
+		udev: map[string]string{
+			"c8:0": "S:disk/by-id/ata-Samsung_SSD\nS:disk/by-id/wwn-0x5002538\n",
+		},
+	})
+
+	devices, err = s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+
+	want = []string{
+		"disk/by-id/ata-Samsung_SSD",
+		"disk/by-id/wwn-0x5002538",
+		"disk/by-path/pci-0000:00:1f.2-ata-1",
+	}
+	if !reflect.DeepEqual(devices[0].Links, want) {
+		t.Errorf("want Links %v got %v", want, devices[0].Links)
+	}
+}
+
+func TestScanDevicesEnvValues(t *testing.T) {
+	dev := "virtual/misc/foo"
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			dev + "/uevent": "DEVNAME=foo\nDEVNAME=bar\nHID_NAME=a=b==c\n" +
+				"DEVLINKS=/dev/foo-1 /dev/foo-2\nDEVLINKS=/dev/foo-2 /dev/foo-3\n" +
+				"TAGS=:seat:\n",
+			"virtual/misc/bar/uevent": "DEVNAME=bar\nTAGS=\nCURRENT_TAGS=\n",
+			dev + "/dev":              "10:200\n",
+		},
+		udev: map[string]string{
+			"c10:200": "E:TAGS=:uaccess:seat:\nE:CURRENT_TAGS=:uaccess:\nE:ID_SERIAL=x=y\n",
+		},
+	})
+
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 2 {
+		t.Fatalf("wanted 2 devices got %d", len(devices))
+	}
+
+	bar := findDevice(t, devices, "virtual/misc/bar")
+	for _, k := range []string{"TAGS", "CURRENT_TAGS"} {
+		if v, ok := bar.Env[k]; !ok || v != "" {
+			t.Errorf("want empty %s kept got %q, %v", k, v, ok)
+		}
+	}
+	devices = []*types.Device{findDevice(t, devices, dev)}
+
+	want := map[string]string{
+		"DEVNAME":      "bar",
+		"HID_NAME":     "a=b==c",
+		"ID_SERIAL":    "x=y",
+		"DEVLINKS":     "/dev/foo-1 /dev/foo-2 /dev/foo-3",
+		"TAGS":         ":seat:uaccess:",
+		"CURRENT_TAGS": ":uaccess:",
+	}
+	for k, v := range want {
+		if devices[0].Env[k] != v {
+			t.Errorf("want %s %q got %q", k, v, devices[0].Env[k])
+		}
+	}
+
+	wantLinks := []string{"foo-1", "foo-2", "foo-3"}
+	if !reflect.DeepEqual(devices[0].Links, wantLinks) {
+		t.Errorf("want Links %v got %v", wantLinks, devices[0].Links)
+	}
+}
+
+func TestScanDevicesUSBPortPath(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleUSBPortPath("2-1.2"))
+
+	s := newDemoScanner(t, WithMatcher(m))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+
+	if devices[0].Env["ID_MODEL"] != "USB_Optical_Mouse" {
+		t.Errorf("want ID_MODEL %s got %v", "USB_Optical_Mouse", devices[0].Env["ID_MODEL"])
+	}
+}
+
+func TestScanDevicesWithAttrConcurrency(t *testing.T) {
+	files := map[string]string{
+		"virtual/misc/foo/uevent": "DEVNAME=foo\n",
+	}
+	for i := range 200 {
+		files[fmt.Sprintf("virtual/misc/foo/attr%03d", i)] = fmt.Sprintf("value %d\n", i)
+	}
+	f := fixture{devices: files}
+
+	tests := []struct {
+		concurrency []Option
+		serial      []Option
+	}{
+		{concurrency: []Option{WithAttrConcurrency(8)}},
+		{concurrency: []Option{WithAttrConcurrency(500), WithConcurrency(4)}},
+		{
+			concurrency: []Option{WithAttrConcurrency(8), WithMaxAttrsPerDevice(10)},
+			serial:      []Option{WithMaxAttrsPerDevice(10)},
+		},
+	}
+
+	for _, tc := range tests {
+		serial, err := newFixtureScanner(t, f, tc.serial...).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		devices, err := newFixtureScanner(t, f, tc.concurrency...).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		if len(devices) != 1 || len(serial) != 1 {
+			t.Fatalf("wanted 1 device got %d and %d", len(devices), len(serial))
+		}
+
+		if !reflect.DeepEqual(devices[0].Attrs, serial[0].Attrs) {
+			t.Errorf("want attrs %v got %v", serial[0].Attrs, devices[0].Attrs)
+		}
+	}
+}
+
+func TestScanDevicesWithTagMatcher(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleTag("seat"))
+
+	s := newDemoScanner(t, WithMatcher(m))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	if len(devices) != 4 {
+		t.Fatalf("wanted 4 devices got %d", len(devices))
+	}
+}
+
+func TestScanDevicesWithUSBSpeedMatcher(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleUSBSpeed(480))
+
+	s := newDemoScanner(t, WithMatcher(m))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	// The root hubs and hubs, leaving out the low-speed mouse and the
+	// full-speed printer.
+	want := []string{
+		"pci0000:00/0000:00:1a.0/usb1",
+		"pci0000:00/0000:00:1a.0/usb1/1-1",
+		"pci0000:00/0000:00:1d.0/usb2",
+		"pci0000:00/0000:00:1d.0/usb2/2-1",
+	}
+	if got := devpaths(devices); !slices.Equal(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
+
+func TestScanDevicesWithIDMatcher(t *testing.T) {
+	m := matcher.NewMatcher()
+	m.AddRule(matcher.NewRuleVendorID("046d"))
+	m.AddRule(matcher.NewRuleProductID("c05b"))
+
+	s := newDemoScanner(t, WithMatcher(m))
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	// The mouse USB device, alongside its hidraw, event and mouse
+	// devices that inherit the IDs.
+	if len(devices) != 4 {
+		t.Fatalf("wanted 4 devices got %d", len(devices))
+	}
+}
+
+func TestScanDevicesWithWarnOnEmptyFilter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := WithLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	tests := []struct {
+		opts []Option
+		warn bool
+	}{
+		{opts: []Option{WithPathFilterPattern(regexp.MustCompile("^no-match$")), WithWarnOnEmptyFilter(true)}, warn: true},
+		{opts: []Option{WithPathFilterPattern(regexp.MustCompile("^no-match$"))}},
+		{opts: []Option{WithPathFilterPattern(regexp.MustCompile("^no-match$")), WithWarnOnEmptyFilter(true), WithSyntheticRoot(true)}, warn: true},
+		{opts: []Option{WithPathFilterPattern(regexp.MustCompile("^platform/")), WithWarnOnEmptyFilter(true)}},
+	}
+
+	for _, tc := range tests {
+		buf.Reset()
+
+		_, err := newDemoScanner(t, append(tc.opts, logger)...).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan the demo tree", err)
+		}
+
+		got := strings.Contains(buf.String(), "level=WARN msg=\"path filter pattern matched no devices\" pattern=^no-match$")
+		if got != tc.warn {
+			t.Errorf("want warning %v got log %q", tc.warn, buf.String())
+		}
+	}
+}
+
+func TestScanDevicesWithLogger(t *testing.T) {
+	var def, custom bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&def, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	const msg = "msg=\"device attrs truncated\""
+
+	_, err := newDemoScanner(t, WithMaxAttrsPerDevice(1)).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if !strings.Contains(def.String(), msg) {
+		t.Errorf("want debug message on the default logger got %q", def.String())
+	}
+
+	def.Reset()
+	logger := slog.New(slog.NewTextHandler(&custom, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, err = newDemoScanner(t, WithMaxAttrsPerDevice(1), WithLogger(logger)).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+	if !strings.Contains(custom.String(), msg) {
+		t.Errorf("want debug message on the custom logger got %q", custom.String())
+	}
+	if def.Len() != 0 {
+		t.Errorf("want nothing on the default logger got %q", def.String())
+	}
+}
+
+func TestScanDevicesWithSubsystemFilter(t *testing.T) {
+	usb := "pci0000:00/0000:00:14.0/usb1/1-1"
+	intf := usb + "/1-1:1.0"
+	hid := intf + "/0003:046D:C52B.0001"
+	f := fixture{
+		devices: map[string]string{
+			usb + "/uevent":                     "DEVTYPE=usb_device\n",
+			intf + "/uevent":                    "DEVTYPE=usb_interface\n",
+			hid + "/uevent":                     "HID_NAME=Logitech USB Receiver\n",
+			hid + "/hidraw/hidraw0/uevent":      "DEVNAME=hidraw0\n",
+			hid + "/input/input3/uevent":        "NAME=\"Logitech USB Receiver\"\n",
+			hid + "/input/input3/event3/uevent": "DEVNAME=input/event3\n",
+		},
+		links: map[string]string{
+			usb + "/subsystem":                     "../../../../../bus/usb",
+			intf + "/subsystem":                    "../../../../../../bus/usb",
+			hid + "/subsystem":                     "../../../../../../../bus/hid",
+			hid + "/hidraw/hidraw0/subsystem":      "../../../../../../../../../class/hidraw",
+			hid + "/input/input3/subsystem":        "../../../../../../../../../class/input",
+			hid + "/input/input3/event3/subsystem": "../../../../../../../../../../class/input",
+		},
+	}
+
+	devices, err := newFixtureScanner(t, f).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 6 {
+		t.Fatalf("wanted 6 devices got %d", len(devices))
+	}
+
+	devices, err = newFixtureScanner(t, f, WithSubsystemFilter("input", "hidraw")).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 3 {
+		t.Fatalf("wanted 3 devices got %d", len(devices))
+	}
+
+	for _, d := range devices {
+		if d.Subsystem != "input" && d.Subsystem != "hidraw" {
+			t.Errorf("want input or hidraw device got %q", d.Subsystem)
+		}
+
+		p := d.GetParentWithSubsystem("usb")
+		if p == nil || p.Devpath != intf {
+			t.Errorf("want %q usb ancestor %q got %v", d.Devpath, intf, p)
+		}
+	}
+}
+
+func TestScanDevicesDriver(t *testing.T) {
+	usb := "pci0000:00/0000:00:14.0/usb1/1-1"
+	intf := usb + "/1-1:1.0"
+	f := fixture{
+		devices: map[string]string{
+			usb + "/uevent":              "DEVTYPE=usb_device\nDRIVER=usb\n",
+			intf + "/uevent":             "DEVTYPE=usb_interface\nDRIVER=usbhid\n",
+			intf + "/ep_81/uevent":       "DEVTYPE=usb_endpoint\n",
+			"platform/serial8250/uevent": "DRIVER=serial8250\n",
+		},
+		links: map[string]string{
+			usb + "/driver":  "../../../../../bus/usb/drivers/usb",
+			intf + "/driver": "../../../../../../bus/usb/drivers/usbhid-custom",
+		},
+	}
+
+	devices, err := newFixtureScanner(t, f).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	want := map[string]string{
+		usb:                   "usb",
+		intf:                  "usbhid-custom",
+		intf + "/ep_81":       "",
+		"platform/serial8250": "serial8250",
+	}
+	for devpath, driver := range want {
+		if d := findDevice(t, devices, devpath); d.Driver != driver {
+			t.Errorf("%s: want driver %q got %q", devpath, driver, d.Driver)
+		}
+	}
+}
+
+func TestScanDevicesWakeup(t *testing.T) {
+	usb := "pci0000:00/0000:00:14.0/usb1/1-1"
+	f := fixture{
+		devices: map[string]string{
+			usb + "/uevent":               "DEVTYPE=usb_device\n",
+			usb + "/power/wakeup":         "enabled\n",
+			usb + "/power/control":        "auto\n",
+			"platform/serial8250/uevent":  "",
+			"platform/i8042/uevent":       "",
+			"platform/i8042/power/wakeup": "disabled\n",
+		},
+	}
+
+	for _, opts := range [][]Option{nil, {WithAttrConcurrency(4)}} {
+		devices, err := newFixtureScanner(t, f, opts...).ScanDevices()
+		if err != nil {
+			t.Fatal("failed to scan", err)
+		}
+
+		d := findDevice(t, devices, usb)
+		if enabled, found := d.CanWakeup(); !enabled || !found {
+			t.Errorf("want wakeup enabled got (%v, %v)", enabled, found)
+		}
+		if _, ok := d.Attrs["power/control"]; ok {
+			t.Error("want only the known nested attrs read")
+		}
+
+		d = findDevice(t, devices, "platform/i8042")
+		if enabled, found := d.CanWakeup(); enabled || !found {
+			t.Errorf("want wakeup disabled got (%v, %v)", enabled, found)
+		}
+
+		d = findDevice(t, devices, "platform/serial8250")
+		if _, found := d.CanWakeup(); found {
+			t.Error("want wakeup not found")
+		}
+	}
+}
+
+func TestScanUSBMaxPower(t *testing.T) {
+	hub := "pci0000:00/0000:00:14.0/usb1/1-1"
+	disk := hub + "/1-1.1"
+	devices, err := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			hub + "/uevent":        "DEVTYPE=usb_device\n",
+			hub + "/bmAttributes":  "e0\n",
+			hub + "/bMaxPower":     "0mA\n",
+			disk + "/uevent":       "DEVTYPE=usb_device\n",
+			disk + "/bmAttributes": "80\n",
+			disk + "/bMaxPower":    "500mA\n",
+		},
+	}).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	for devpath, want := range map[string]int{hub: 0, disk: 500} {
+		got, ok := findDevice(t, devices, devpath).USBMaxPowerMilliamps()
+		if !ok || got != want {
+			t.Errorf("%s: want %dmA got %dmA (%v)", devpath, want, got, ok)
+		}
+	}
+}
+
+func TestScanBlockSizes(t *testing.T) {
+	disk := "pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda"
+	devices, err := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			disk + "/uevent":                    "DEVNAME=sda\nDEVTYPE=disk\n",
+			disk + "/queue/logical_block_size":  "512\n",
+			disk + "/queue/physical_block_size": "4096\n",
+			disk + "/queue/rotational":          "0\n",
+			disk + "/sda1/uevent":               "DEVNAME=sda1\nDEVTYPE=partition\n",
+			disk + "/sda1/alignment_offset":     "0\n",
+		},
+	}).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	logical, physical, ok := findDevice(t, devices, disk).BlockSizes()
+	if !ok || logical != 512 || physical != 4096 {
+		t.Errorf("want (512, 4096) got (%d, %d, %v)", logical, physical, ok)
+	}
+
+	if _, _, ok := findDevice(t, devices, disk+"/sda1").BlockSizes(); ok {
+		t.Error("want no block sizes for the partition")
+	}
+}
+
+func TestScanDevicesPath(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	// The derived paths match those set by udev.
+	checked := 0
+	for _, d := range devices {
+		want := d.Env["ID_PATH"]
+		if want == "" {
+			continue
+		}
+
+		delete(d.Env, "ID_PATH")
+		if got := d.Path(); got != want {
+			t.Errorf("%s: want %q got %q", d.Devpath, want, got)
+		}
+		checked++
+	}
+
+	if checked == 0 {
+		t.Fatal("no devices with ID_PATH in the demo tree")
+	}
+}
+
+func TestScanUSBEndpoint(t *testing.T) {
+	intf := "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0"
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			intf + "/uevent":               "DEVTYPE=usb_interface\n",
+			intf + "/ep_81/uevent":         "DEVTYPE=usb_endpoint\n",
+			intf + "/ep_81/bInterval":      "0a\n",
+			intf + "/ep_81/wMaxPacketSize": "0008\n",
+			intf + "/ep_81/interval":       "10ms\n",
+		},
+	})
+
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 2 {
+		t.Fatalf("wanted 2 devices got %d", len(devices))
+	}
+
+	for _, d := range devices {
+		interval, maxPacket, ok := d.USBEndpoint()
+		if d.Devpath == intf {
+			if ok {
+				t.Errorf("want interface not to be an endpoint got %d, %d", interval, maxPacket)
+			}
+			continue
+		}
+
+		if !ok || interval != 10 || maxPacket != 8 {
+			t.Errorf("want endpoint 10, 8, true got %d, %d, %v", interval, maxPacket, ok)
+		}
+	}
+}
+
+func TestSetSysname(t *testing.T) {
+	tests := []struct {
+		devpath     string
+		wantSysname string
+		wantSysnum  string
+	}{
+		{devpath: "pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda3", wantSysname: "sda3", wantSysnum: "3"},
+		{devpath: "virtual/input/input12", wantSysname: "input12", wantSysnum: "12"},
+		{devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2", wantSysname: "2-1.2", wantSysnum: "2"},
+		{devpath: "platform/serial8250", wantSysname: "serial8250", wantSysnum: "8250"},
+		{devpath: "virtual/misc/uinput", wantSysname: "uinput", wantSysnum: ""},
+		{devpath: "pci0000:00/0000:00:1f.2/block/cciss!c0d0", wantSysname: "cciss/c0d0", wantSysnum: "0"},
+	}
+
+	for _, tc := range tests {
+		d := &types.Device{Devpath: tc.devpath}
+		setSysname(d)
+
+		if d.Sysname != tc.wantSysname || d.Sysnum != tc.wantSysnum {
+			t.Errorf("%s: want %q, %q got %q, %q", tc.devpath, tc.wantSysname, tc.wantSysnum, d.Sysname, d.Sysnum)
+		}
+	}
+
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	tty := findDevice(t, devices, "platform/serial8250/tty/ttyS17")
+	if tty.Sysname != "ttyS17" || tty.Sysnum != "17" {
+		t.Errorf("want ttyS17, 17 got %q, %q", tty.Sysname, tty.Sysnum)
+	}
+}
+
+func TestScanDevicesWithErrorHandler(t *testing.T) {
+	f := fixture{
+		devices: map[string]string{
+			"virtual/misc/foo/uevent": "DEVNAME=foo\n",
+			"virtual/misc/bar/dev":    "10:1\n",
+		},
+		links: map[string]string{
+			// uevent links escaping the devices root cannot be read.
+			"virtual/misc/bar/uevent": "../../../../outside",
+		},
+	}
+
+	errs := map[string]error{}
+	s := newFixtureScanner(t, f, WithErrorHandler(func(path string, err error) {
+		errs[path] = err
+	}))
+
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 1 || devices[0].Devpath != "virtual/misc/foo" {
+		t.Fatalf("wanted only the readable device got %d devices", len(devices))
+	}
+
+	if len(errs) != 1 || errs["virtual/misc/bar/uevent"] == nil {
+		t.Fatalf("want error for unreadable device got %v", errs)
+	}
+
+	// The default behaviour is to skip the device silently.
+	devices, err = newFixtureScanner(t, f).ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("wanted 1 device got %d", len(devices))
+	}
+}
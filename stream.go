@@ -0,0 +1,125 @@
+package libudev
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// Stream scans the devices like ScanDevicesContext, sending each one to
+// the returned devices channel as soon as it is read, while the walk is
+// still in progress, for use in pipelines. The subsystem filter, the
+// matcher and WithMaxDevices are applied to each device as it is read.
+//
+// As the tree is not linked yet when devices are sent, their Parent and
+// Children are nil, they lack the VendorID and ProductID inherited from
+// their ancestors, and they are sent in no particular order. For the
+// same reason, WithSyntheticRoot is ignored. When the matcher has rules
+// that depend on the device tree, the whole tree is scanned and linked
+// first, and the matching devices are sent afterwards.
+//
+// Any scan error is sent to the errors channel. Both channels are closed
+// when all devices are sent or ctx is done, whichever happens first.
+func (s *scanner) Stream(ctx context.Context) (<-chan *types.Device, <-chan error) {
+	devCh := make(chan *types.Device)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		defer close(devCh)
+
+		var err error
+		if s.opts.matcher != nil && s.opts.matcher.RequiresTree() {
+			err = s.streamTree(ctx, devCh)
+		} else {
+			err = s.streamWalk(ctx, devCh)
+		}
+		if err != nil {
+			errCh <- err
+		}
+	}()
+
+	return devCh, errCh
+}
+
+// streamWalk sends the devices to devCh while walking the devices root.
+func (s *scanner) streamWalk(ctx context.Context, devCh chan<- *types.Device) error {
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	read, sent := 0, 0
+	paths := make(chan string)
+
+	wait := s.startParsers(paths, func(device *types.Device) {
+		mu.Lock()
+		read++
+		mu.Unlock()
+
+		if !s.hasSubsystem(device) || (s.opts.matcher != nil && !s.opts.matcher.Match(device)) {
+			return
+		}
+
+		mu.Lock()
+		if s.opts.maxDevices > 0 && sent >= s.opts.maxDevices {
+			mu.Unlock()
+			return
+		}
+		sent++
+		if s.opts.maxDevices > 0 && sent == s.opts.maxDevices {
+			// Stop the walk, which is no longer needed.
+			cancel()
+		}
+		mu.Unlock()
+
+		select {
+		case devCh <- device:
+		case <-ctx.Done():
+		}
+	})
+
+	err := s.walk(walkCtx, func(path string) error {
+		select {
+		case paths <- path:
+			return nil
+		case <-walkCtx.Done():
+			return walkCtx.Err()
+		}
+	})
+	close(paths)
+	wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Cancelling the walk once enough devices are sent is not an error.
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	if s.opts.warnOnEmptyFilter && s.opts.pathFilterPattern != nil && read == 0 {
+		s.opts.log().Warn("path filter pattern matched no devices", "pattern", s.opts.pathFilterPattern.String())
+	}
+
+	return nil
+}
+
+// streamTree sends the devices to devCh once the whole tree is scanned.
+func (s *scanner) streamTree(ctx context.Context, devCh chan<- *types.Device) error {
+	devices, err := s.ScanDevicesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range devices {
+		select {
+		case devCh <- d:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
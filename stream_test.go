@@ -0,0 +1,109 @@
+package libudev
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	s := newDemoScanner(t)
+
+	devices, errs := s.Stream(context.Background())
+
+	count := 0
+	for range devices {
+		count++
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal("failed to stream the demo tree", err)
+	}
+
+	if count != 11 {
+		t.Fatalf("wanted 11 devices got %d", count)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	s := newDemoScanner(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	devices, errs := s.Stream(ctx)
+
+	<-devices
+	cancel()
+
+	for range devices {
+	}
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled got %v", err)
+	}
+}
+
+func TestStreamBeforeWalkEnds(t *testing.T) {
+	// The `dev` dir of b fails to be read, blocking the single worker in
+	// the error handler until a device is received, so that the walk is
+	// still in progress when a is sent.
+	release := make(chan struct{})
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			"virtual/misc/a/uevent":     "DEVNAME=a\n",
+			"virtual/misc/b/uevent":     "DEVNAME=b\n",
+			"virtual/misc/b/dev/attr":   "not a dev file\n",
+			"virtual/misc/c/uevent":     "DEVNAME=c\n",
+			"virtual/misc/c/d/e/uevent": "DEVNAME=e\n",
+		},
+	}, WithConcurrency(1), WithErrorHandler(func(string, error) { <-release }))
+
+	devices, errs := s.Stream(context.Background())
+
+	select {
+	case d := <-devices:
+		if d.Devpath != "virtual/misc/a" {
+			t.Errorf("want %q sent first got %q", "virtual/misc/a", d.Devpath)
+		}
+		if d.Parent != nil || d.Children != nil {
+			t.Errorf("want %q not linked got %+v", d.Devpath, d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no device sent while walking")
+	}
+	close(release)
+
+	var got []string
+	for d := range devices {
+		got = append(got, d.Devpath)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal("failed to stream", err)
+	}
+
+	slices.Sort(got)
+	want := []string{"virtual/misc/c", "virtual/misc/c/d/e"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want devices %v got %v", want, got)
+	}
+}
+
+func TestStreamWithMaxDevices(t *testing.T) {
+	s := newDemoScanner(t, WithMaxDevices(2), WithConcurrency(1))
+
+	devices, errs := s.Stream(context.Background())
+
+	count := 0
+	for range devices {
+		count++
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatal("failed to stream the demo tree", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("wanted 2 devices got %d", count)
+	}
+}
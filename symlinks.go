@@ -0,0 +1,82 @@
+package libudev
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// DeviceSymlinks returns the symlinks found in the directory of the
+// device at devpath, mapping their names to their targets. This includes
+// links such as `subsystem` and `driver`, and links within subdirectories
+// that are not devices themselves, such as `slaves/sda`.
+//
+// Targets are resolved lexically, without following any links nor
+// checking that they exist, and relative targets are returned relative
+// to the devices root. They are not confined to it: targets outside of
+// the devices root start with `..` (e.g. `../bus/usb/drivers/usbhid`),
+// and absolute targets are returned unchanged. Callers must not open
+// them outside of an os.Root if the tree is untrusted.
+func (s *scanner) DeviceSymlinks(devpath string) (map[string]string, error) {
+	path, err := s.devicePath(devpath)
+	if err != nil {
+		return nil, err
+	}
+
+	links := map[string]string{}
+	err = s.readSymlinks(path, "", links)
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// readSymlinks reads the symlinks in dir into links, with their names
+// prefixed by prefix. Subdirectories are only descended into from the
+// device dir, and only when they are not devices.
+func (s *scanner) readSymlinks(dir, prefix string, links map[string]string) error {
+	entries, err := fs.ReadDir(s.opts.devicesRoot.FS(), dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+
+		if e.IsDir() {
+			if prefix != "" {
+				continue
+			}
+
+			_, err := s.opts.devicesRoot.Lstat(filepath.Join(path, "uevent"))
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+
+			if err := s.readSymlinks(path, e.Name()+"/", links); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if e.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+
+		target, err := s.opts.devicesRoot.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		links[prefix+e.Name()] = target
+	}
+
+	return nil
+}
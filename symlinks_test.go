@@ -0,0 +1,64 @@
+package libudev
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeviceSymlinks(t *testing.T) {
+	md := "virtual/block/md0"
+	intf := "pci0000:00/0000:00:14.0/usb1/1-1/1-1:1.0"
+	s := newFixtureScanner(t, fixture{
+		devices: map[string]string{
+			md + "/uevent":         "DEVNAME=md0\n",
+			md + "/dev":            "9:0\n",
+			md + "/md/level":       "raid1\n",
+			intf + "/uevent":       "DEVTYPE=usb_interface\n",
+			intf + "/ep_81/uevent": "DEVTYPE=usb_endpoint\n",
+		},
+		links: map[string]string{
+			md + "/subsystem":         "../../../../class/block",
+			md + "/slaves/sda1":       "../../../../pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1",
+			md + "/md/dev-sda1/block": "../../../../../pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1",
+			md + "/holders/escape":    "../../../../../../etc/passwd",
+			md + "/holders/absolute":  "/etc/passwd",
+			intf + "/subsystem":       "../../../../../../bus/usb",
+			intf + "/driver":          "../../../../../../bus/usb/drivers/usbhid",
+			intf + "/ep_81/subsystem": "../../../../../../../class/usb_endpoint",
+		},
+	})
+
+	links, err := s.DeviceSymlinks(md)
+	if err != nil {
+		t.Fatal("failed to read symlinks", err)
+	}
+
+	want := map[string]string{
+		"subsystem":   "../class/block",
+		"slaves/sda1": "pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sda/sda1",
+		// Escaping and absolute targets are not confined to the root.
+		"holders/escape":   "../../etc/passwd",
+		"holders/absolute": "/etc/passwd",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("want %v got %v", want, links)
+	}
+
+	links, err = s.DeviceSymlinks(filepath.Join(s.opts.devicesRoot.Name(), intf))
+	if err != nil {
+		t.Fatal("failed to read symlinks", err)
+	}
+
+	want = map[string]string{
+		"subsystem": "../bus/usb",
+		"driver":    "../bus/usb/drivers/usbhid",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("want %v got %v", want, links)
+	}
+
+	if _, err := s.DeviceSymlinks("../outside"); err == nil {
+		t.Error("want error for path outside devices root")
+	}
+}
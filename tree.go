@@ -0,0 +1,255 @@
+package libudev
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/qubesome/libudev/types"
+)
+
+// RelinkParents walks the Children of roots and sets each child's Parent,
+// restoring the tree links of devices decoded from JSON into plain
+// structs.
+func RelinkParents(roots []*types.Device) {
+	seen := map[*types.Device]bool{}
+
+	var relink func(d *types.Device)
+	relink = func(d *types.Device) {
+		if seen[d] {
+			return
+		}
+		seen[d] = true
+
+		for _, c := range d.Children {
+			c.Parent = d
+			relink(c)
+		}
+	}
+
+	for _, r := range roots {
+		relink(r)
+	}
+}
+
+// ValidateTree verifies that the trees starting at roots are consistent:
+// every child must point back to its parent, and no device can be
+// reached more than once.
+func ValidateTree(roots []*types.Device) error {
+	seen := map[*types.Device]bool{}
+
+	var validate func(d *types.Device) error
+	validate = func(d *types.Device) error {
+		if seen[d] {
+			return fmt.Errorf("device %q reached more than once", d.Devpath)
+		}
+		seen[d] = true
+
+		for _, c := range d.Children {
+			if c.Parent != d {
+				return fmt.Errorf("device %q is not linked to its parent %q", c.Devpath, d.Devpath)
+			}
+
+			if err := validate(c); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, r := range roots {
+		if err := validate(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SamePhysicalDevice reports whether a and b belong to the same physical
+// device, by comparing their nearest ancestors (including themselves)
+// that carry the `idVendor` and `idProduct` attrs, alongside the optional
+// `serial` attr.
+//
+// This groups all nodes of a peripheral, such as the interfaces, input
+// and hidraw devices of a USB mouse.
+func SamePhysicalDevice(a, b *types.Device) bool {
+	pa := physicalDevice(a)
+	pb := physicalDevice(b)
+	if pa == nil || pb == nil {
+		return false
+	}
+
+	if pa == pb {
+		return true
+	}
+
+	return pa.Devpath == pb.Devpath &&
+		pa.Attrs["idVendor"] == pb.Attrs["idVendor"] &&
+		pa.Attrs["idProduct"] == pb.Attrs["idProduct"] &&
+		pa.Attrs["serial"] == pb.Attrs["serial"]
+}
+
+// physicalDevice returns the nearest device in d's ancestry, including d,
+// that identifies a physical device.
+func physicalDevice(d *types.Device) *types.Device {
+	seen := map[*types.Device]bool{}
+	for ; d != nil && !seen[d]; d = d.Parent {
+		seen[d] = true
+
+		_, vok := d.Attrs["idVendor"]
+		_, pok := d.Attrs["idProduct"]
+		if vok && pok {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// CommonAncestor returns the deepest device that is an ancestor of all
+// devices, or nil if they belong to different trees. A device is
+// considered an ancestor of itself, so CommonAncestor of a device and
+// one of its descendants returns the former.
+func CommonAncestor(devices []*types.Device) *types.Device {
+	if len(devices) == 0 {
+		return nil
+	}
+
+	chains := make([]map[*types.Device]bool, 0, len(devices)-1)
+	for _, d := range devices[1:] {
+		chain := map[*types.Device]bool{}
+		for ; d != nil && !chain[d]; d = d.Parent {
+			chain[d] = true
+		}
+		chains = append(chains, chain)
+	}
+
+	seen := map[*types.Device]bool{}
+	for d := devices[0]; d != nil && !seen[d]; d = d.Parent {
+		seen[d] = true
+
+		common := true
+		for _, chain := range chains {
+			if !chain[d] {
+				common = false
+				break
+			}
+		}
+
+		if common {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// Roots returns a new slice with the devices that have no Parent, such
+// as `pci0000:00` or `platform`, which are the entry points of the tree.
+func Roots(devices []*types.Device) []*types.Device {
+	var roots []*types.Device
+	for _, d := range devices {
+		if d.Parent == nil {
+			roots = append(roots, d)
+		}
+	}
+
+	return roots
+}
+
+// Walk traverses the device tree depth-first, starting from the devices
+// without a Parent and recursing through their Children. fn is called
+// for each device with its depth, where roots are at depth 0. The walk
+// stops at the first error returned by fn, which is returned by Walk.
+//
+// Devices are visited at most once, so malformed trees that link back
+// on themselves do not loop forever.
+func Walk(devices []*types.Device, fn func(d *types.Device, depth int) error) error {
+	seen := map[*types.Device]bool{}
+
+	var walk func(d *types.Device, depth int) error
+	walk = func(d *types.Device, depth int) error {
+		if seen[d] {
+			return nil
+		}
+		seen[d] = true
+
+		if err := fn(d, depth); err != nil {
+			return err
+		}
+
+		for _, c := range d.Children {
+			if err := walk(c, depth+1); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, d := range Roots(devices) {
+		if err := walk(d, 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FreezeTree returns a deep copy of the trees starting at roots, which
+// shares no memory with the original devices: the Env, Attrs, Tags,
+// Links, Descriptors and Children of each device are copied, and the
+// Parent and Children links point to the copies. The Parent of a root is
+// only kept when the parent is part of the copied trees.
+//
+// The returned snapshot is never modified by this package, so it is safe
+// to share between goroutines as long as callers treat it as read-only.
+func FreezeTree(roots []*types.Device) []*types.Device {
+	copies := map[*types.Device]*types.Device{}
+
+	var clone func(d *types.Device)
+	clone = func(d *types.Device) {
+		if _, ok := copies[d]; ok {
+			return
+		}
+
+		c := *d
+		c.Env = maps.Clone(d.Env)
+		c.Attrs = maps.Clone(d.Attrs)
+		c.Tags = slices.Clone(d.Tags)
+		c.Links = slices.Clone(d.Links)
+		c.Descriptors = slices.Clone(d.Descriptors)
+		for i := range c.Descriptors {
+			c.Descriptors[i].Raw = slices.Clone(c.Descriptors[i].Raw)
+		}
+		copies[d] = &c
+
+		for _, child := range d.Children {
+			clone(child)
+		}
+	}
+
+	for _, r := range roots {
+		clone(r)
+	}
+
+	for _, c := range copies {
+		c.Parent = copies[c.Parent]
+		if c.Children != nil {
+			children := make([]*types.Device, len(c.Children))
+			for i, child := range c.Children {
+				children[i] = copies[child]
+			}
+			c.Children = children
+		}
+	}
+
+	frozen := make([]*types.Device, len(roots))
+	for i, r := range roots {
+		frozen[i] = copies[r]
+	}
+
+	return frozen
+}
@@ -0,0 +1,429 @@
+package libudev
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/qubesome/libudev/types"
+)
+
+func TestSamePhysicalDevice(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	mouse := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2")
+	event := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2")
+	hidraw := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/hidraw/hidraw0")
+	printer := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4/2-1.4:1.0/usbmisc/lp0")
+	tty := findDevice(t, devices, "platform/serial8250/tty/ttyS17")
+
+	if !SamePhysicalDevice(event, mouse) {
+		t.Error("want mouse event device and its parent to be the same physical device")
+	}
+	if !SamePhysicalDevice(event, hidraw) {
+		t.Error("want mouse event and hidraw devices to be the same physical device")
+	}
+	if SamePhysicalDevice(event, printer) {
+		t.Error("want mouse and printer to be different physical devices")
+	}
+	if SamePhysicalDevice(tty, tty) {
+		t.Error("want device without physical ancestor to not match")
+	}
+}
+
+func TestRelinkParents(t *testing.T) {
+	data := `[{
+		"Devpath": "pci0000:00/0000:00:1d.0/usb2",
+		"Env": {"DEVNAME": "bus/usb/002/001"},
+		"Children": [{
+			"Devpath": "pci0000:00/0000:00:1d.0/usb2/2-1",
+			"Env": {"DEVNAME": "bus/usb/002/002"},
+			"Children": [
+				{"Devpath": "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2"},
+				{"Devpath": "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4"}
+			]
+		}]
+	}]`
+
+	roots := decodePlainTree(t, []byte(data))
+	hub := roots[0].Children[0]
+	mouse := hub.Children[0]
+	if hub.Parent != nil || mouse.Parent != nil {
+		t.Fatal("want plain tree decoded without parents")
+	}
+
+	if err := ValidateTree(roots); err == nil {
+		t.Fatal("want error validating tree without parents")
+	}
+
+	RelinkParents(roots)
+
+	if err := ValidateTree(roots); err != nil {
+		t.Fatal("failed to validate relinked tree", err)
+	}
+
+	if hub.Parent != roots[0] {
+		t.Error("want hub linked to root")
+	}
+	if mouse.Parent != hub {
+		t.Error("want mouse linked to hub")
+	}
+	for _, c := range hub.Children {
+		if c.Parent != hub {
+			t.Errorf("want %q linked to hub", c.Devpath)
+		}
+	}
+
+	if roots[0].Parent != nil {
+		t.Error("want root without parent")
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	roots := Roots(devices)
+
+	if err := ValidateTree(roots); err != nil {
+		t.Fatal("failed to validate scanned tree", err)
+	}
+
+	a := &types.Device{Devpath: "a"}
+	b := &types.Device{Devpath: "a/b", Parent: a}
+	a.Children = []*types.Device{b}
+	b.Children = []*types.Device{a}
+	a.Parent = b
+
+	if err := ValidateTree([]*types.Device{a}); err == nil {
+		t.Fatal("want error validating cyclic tree")
+	}
+}
+
+func TestCommonAncestor(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	hub := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1")
+	mouse := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2")
+	printer := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4")
+	lp := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4/2-1.4:1.0/usbmisc/lp0")
+	event := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2")
+	hidraw := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/hidraw/hidraw0")
+	tty := findDevice(t, devices, "platform/serial8250/tty/ttyS17")
+
+	tests := []struct {
+		name    string
+		devices []*types.Device
+		want    *types.Device
+	}{
+		{name: "siblings", devices: []*types.Device{mouse, printer}, want: hub},
+		{name: "cousins", devices: []*types.Device{event, lp}, want: hub},
+		{name: "same interface", devices: []*types.Device{event, hidraw}, want: mouse},
+		{name: "descendant", devices: []*types.Device{event, mouse}, want: mouse},
+		{name: "single", devices: []*types.Device{lp}, want: lp},
+		{name: "different roots", devices: []*types.Device{tty, mouse}, want: nil},
+		{name: "empty", devices: nil, want: nil},
+	}
+
+	for _, tc := range tests {
+		if got := CommonAncestor(tc.devices); got != tc.want {
+			t.Errorf("%s: want %v got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	depths := map[string]int{}
+	err = Walk(devices, func(d *types.Device, depth int) error {
+		if _, ok := depths[d.Devpath]; ok {
+			t.Errorf("device %q visited twice", d.Devpath)
+		}
+		depths[d.Devpath] = depth
+		return nil
+	})
+	if err != nil {
+		t.Fatal("failed to walk the demo tree", err)
+	}
+
+	if len(depths) != len(devices) {
+		t.Fatalf("wanted %d devices visited got %d", len(devices), len(depths))
+	}
+
+	want := map[string]int{
+		"pci0000:00/0000:00:1d.0/usb2":                                                        0,
+		"pci0000:00/0000:00:1d.0/usb2/2-1":                                                    1,
+		"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4/2-1.4:1.0/usbmisc/lp0":                        3,
+		"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/hidraw/hidraw0": 3,
+		"platform/serial8250/tty/ttyS17":                                                      0,
+	}
+	for devpath, depth := range want {
+		if depths[devpath] != depth {
+			t.Errorf("want %q depth %d got %d", devpath, depth, depths[devpath])
+		}
+	}
+
+	errStop := errors.New("stop")
+	stopped := false
+	err = Walk(devices, func(d *types.Device, depth int) error {
+		if stopped {
+			t.Errorf("device %q visited after stopping", d.Devpath)
+		}
+		if depth == 1 {
+			stopped = true
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("want errStop got %v", err)
+	}
+
+	visited := 0
+
+	a := &types.Device{Devpath: "a"}
+	b := &types.Device{Devpath: "a/b", Parent: a}
+	a.Children = []*types.Device{b}
+	b.Children = []*types.Device{a}
+
+	err = Walk([]*types.Device{a, b}, func(*types.Device, int) error {
+		visited++
+		return nil
+	})
+	if err != nil || visited != 2 {
+		t.Errorf("want cyclic tree visited once got %d visits, %v", visited, err)
+	}
+}
+
+func findDevice(t *testing.T, devices []*types.Device, devpath string) *types.Device {
+	t.Helper()
+
+	for _, d := range devices {
+		if d.Devpath == devpath {
+			return d
+		}
+	}
+
+	t.Fatalf("device %q not found", devpath)
+	return nil
+}
+
+func TestJSONSnapshot(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	roots := Roots(devices)
+
+	data, err := json.Marshal(roots)
+	if err != nil {
+		t.Fatal("failed to marshal the demo tree", err)
+	}
+
+	var got []*types.Device
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal("failed to unmarshal the demo tree", err)
+	}
+
+	if err := ValidateTree(got); err != nil {
+		t.Fatal("failed to validate unmarshalled tree", err)
+	}
+
+	count := 0
+	var visit func(ds []*types.Device)
+	visit = func(ds []*types.Device) {
+		for _, d := range ds {
+			count++
+			visit(d.Children)
+		}
+	}
+	visit(got)
+
+	if count != len(devices) {
+		t.Errorf("wanted %d devices got %d", len(devices), count)
+	}
+
+	// Decoding while bypassing the custom unmarshaler leaves the parents
+	// unset, which RelinkParents can fix.
+	plainRoots := decodePlainTree(t, data)
+	hub := plainRoots[1].Children[0]
+	if hub.Devpath != "pci0000:00/0000:00:1d.0/usb2/2-1" || len(hub.Children) == 0 {
+		t.Fatalf("want the usb2 hub with children got %q", hub.Devpath)
+	}
+	grandchild := hub.Children[0]
+	if hub.Parent != nil || grandchild.Parent != nil {
+		t.Fatal("want plain tree decoded without parents")
+	}
+
+	RelinkParents(plainRoots)
+	if err := ValidateTree(plainRoots); err != nil {
+		t.Fatal("failed to validate relinked tree", err)
+	}
+	if grandchild.Parent != hub {
+		t.Errorf("want %q linked to %q", grandchild.Devpath, hub.Devpath)
+	}
+}
+
+// plainDevice mirrors the JSON encoding of a device tree, so that it can
+// be decoded without the custom unmarshaler, which links the parents.
+type plainDevice struct {
+	Devpath  string
+	Env      map[string]string
+	Children []*plainDevice
+}
+
+// decodePlainTree decodes the JSON encoded roots of a device tree, with
+// all the Parent links left nil.
+func decodePlainTree(t *testing.T, data []byte) []*types.Device {
+	t.Helper()
+
+	var plain []*plainDevice
+	if err := json.Unmarshal(data, &plain); err != nil {
+		t.Fatal("failed to unmarshal into plain structs", err)
+	}
+
+	var convert func(p *plainDevice) *types.Device
+	convert = func(p *plainDevice) *types.Device {
+		d := &types.Device{Devpath: p.Devpath, Env: p.Env}
+		for _, c := range p.Children {
+			d.Children = append(d.Children, convert(c))
+		}
+		return d
+	}
+
+	roots := make([]*types.Device, 0, len(plain))
+	for _, p := range plain {
+		roots = append(roots, convert(p))
+	}
+
+	return roots
+}
+
+func TestFreezeTree(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	roots := Roots(devices)
+
+	frozen := FreezeTree(roots)
+	if len(frozen) != len(roots) {
+		t.Fatalf("wanted %d roots got %d", len(roots), len(frozen))
+	}
+
+	if err := ValidateTree(frozen); err != nil {
+		t.Fatal("frozen tree is invalid", err)
+	}
+
+	originals := map[*types.Device]bool{}
+	_ = Walk(roots, func(d *types.Device, _ int) error {
+		originals[d] = true
+		return nil
+	})
+
+	var copies []*types.Device
+	_ = Walk(frozen, func(d *types.Device, _ int) error {
+		if originals[d] {
+			t.Errorf("device %q shared with the original tree", d.Devpath)
+		}
+		copies = append(copies, d)
+		return nil
+	})
+
+	if len(copies) != len(devices) {
+		t.Fatalf("wanted %d devices got %d", len(devices), len(copies))
+	}
+
+	orig := findDevice(t, devices, "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2")
+	frozenMouse := findDevice(t, copies, orig.Devpath)
+	if frozenMouse.Parent.Devpath != orig.Parent.Devpath {
+		t.Errorf("want parent %q got %q", orig.Parent.Devpath, frozenMouse.Parent.Devpath)
+	}
+
+	orig.Env["DEVTYPE"] = "changed"
+	orig.Attrs["idVendor"] = "changed"
+	orig.Tags = append(orig.Tags[:0], "changed")
+	if frozenMouse.Env["DEVTYPE"] == "changed" || frozenMouse.Attrs["idVendor"] == "changed" {
+		t.Error("frozen device changed along with the original")
+	}
+	if slices.Contains(frozenMouse.Tags, "changed") {
+		t.Error("frozen tags changed along with the original")
+	}
+}
+
+// TestFreezeTreeConcurrentReaders is meant to be run with -race.
+func TestFreezeTreeConcurrentReaders(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	roots := Roots(devices)
+	frozen := FreezeTree(roots)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			_ = Walk(frozen, func(d *types.Device, _ int) error {
+				_ = d.Env["DEVNAME"]
+				_ = d.Attrs["idVendor"]
+				_ = d.GetParentWithSubsystem("usb")
+				_, _ = d.MarshalJSON()
+				return nil
+			})
+		})
+	}
+
+	// Writes to the original tree must not race with the readers.
+	for _, d := range devices {
+		d.Env["DEVTYPE"] = "changed"
+		d.Children = nil
+	}
+
+	wg.Wait()
+}
+
+func TestRoots(t *testing.T) {
+	s := newDemoScanner(t)
+	devices, err := s.ScanDevices()
+	if err != nil {
+		t.Fatal("failed to scan the demo tree", err)
+	}
+
+	got := devpaths(Roots(devices))
+	want := []string{"pci0000:00/0000:00:1a.0/usb1", "pci0000:00/0000:00:1d.0/usb2", "platform/serial8250/tty/ttyS17"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+
+	if len(devices) != 11 {
+		t.Errorf("input slice modified, got %d devices", len(devices))
+	}
+
+	if got := Roots(nil); len(got) != 0 {
+		t.Errorf("want no roots got %v", got)
+	}
+}
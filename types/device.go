@@ -1,17 +1,341 @@
 // Package types contains data structures
 package types
 
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// usbPortPathPattern matches the USB device (e.g. `1-1.2.3`) and
+// interface (e.g. `1-1.2.3:1.0`) sysnames, capturing the port path.
+var usbPortPathPattern = regexp.MustCompile(`^([0-9]+-[0-9]+(?:\.[0-9]+)*)(?::[0-9]+\.[0-9]+)?$`)
+
+// pciSlotPattern matches the PCI device sysnames (e.g. `0000:00:1d.0`).
+var pciSlotPattern = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-9a-f]$`)
+
+// usbEndpointPattern matches the USB endpoint sysnames (e.g. `ep_81`).
+var usbEndpointPattern = regexp.MustCompile(`^ep_[0-9a-f]{2}$`)
+
 // Device structure describing the device.
 type Device struct {
 	Devpath         string
+	Sysname         string
+	Sysnum          string
+	Subsystem       string
+	Driver          string
 	Env             map[string]string
 	Attrs           map[string]string
 	Tags            []string
 	UsecInitialized string
 
+	// Links holds the symlinks to the device node, relative to /dev
+	// (e.g. `input/by-id/usb-Logitech_USB_Optical_Mouse-mouse`).
+	Links []string
+
 	VendorID  string
 	ProductID string
 
+	// Descriptors holds the USB descriptors of the device, which are only
+	// read when scanning WithParseDescriptors.
+	Descriptors []USBDescriptor
+
+	// Synthetic is set for the root device added when scanning
+	// WithSyntheticRoot, which does not exist in sysfs.
+	Synthetic bool
+
 	Parent   *Device
 	Children []*Device
 }
+
+// DecodedEnv returns the value of the key in Env decoded with
+// UnescapeUdev, which is handy for the udev encoded properties (e.g.
+// `ID_MODEL_ENC=USB\x20Optical\x20Mouse` becomes `USB Optical Mouse`).
+func (d *Device) DecodedEnv(key string) string {
+	return UnescapeUdev(d.Env[key])
+}
+
+// UnescapeUdev decodes the `\xNN` hex escapes that udev uses in encoded
+// property values (e.g. `ID_MODEL_ENC`) back into their bytes. Malformed
+// escapes are left verbatim.
+func UnescapeUdev(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// ConnectorStatus returns the status of a DRM connector, which is one of
+// `connected`, `disconnected` or `unknown`. For devices that are not DRM
+// connectors an empty string is returned.
+func (d *Device) ConnectorStatus() string {
+	if d.Subsystem != "drm" || d.Env["DEVTYPE"] != "drm_connector" {
+		return ""
+	}
+
+	return d.Attrs["status"]
+}
+
+// GetParentWithSubsystem walks up the Parent chain and returns the first
+// ancestor whose Subsystem equals subsystem, or nil if none is found.
+func (d *Device) GetParentWithSubsystem(subsystem string) *Device {
+	seen := map[*Device]bool{d: true}
+	for p := d.Parent; p != nil && !seen[p]; p = p.Parent {
+		if p.Subsystem == subsystem {
+			return p
+		}
+		seen[p] = true
+	}
+
+	return nil
+}
+
+// InputPhys returns the physical path of an input device (e.g.
+// `usb-0000:00:14.0-1/input0`), or an empty string if not available.
+func (d *Device) InputPhys() string {
+	return d.Attrs["phys"]
+}
+
+// InputUniq returns the unique identifier of an input device (e.g. its
+// serial number), or an empty string if not available.
+func (d *Device) InputUniq() string {
+	return d.Attrs["uniq"]
+}
+
+// USBPortPath returns the physical port path of a USB device or interface
+// (e.g. `1-1.2` for both `1-1.2` and `1-1.2:1.0`), extracted from its
+// sysname. For other devices, including USB root hubs, an empty string
+// is returned.
+func (d *Device) USBPortPath() string {
+	m := usbPortPathPattern.FindStringSubmatch(path.Base(d.Devpath))
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+// CanWakeup reports whether the device is enabled to wake the system up,
+// based on its `power/wakeup` attr. The second returned bool is false
+// when the device does not support wakeup, or the attr is unknown.
+func (d *Device) CanWakeup() (bool, bool) {
+	switch d.Attrs["power/wakeup"] {
+	case "enabled":
+		return true, true
+	case "disabled":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// BlockSizes returns the logical and physical block sizes of a block
+// device, in bytes, parsed from its `queue/logical_block_size` and
+// `queue/physical_block_size` attrs. The returned bool is false when
+// either attr is missing or malformed, as for partitions.
+func (d *Device) BlockSizes() (int, int, bool) {
+	logical, err := strconv.ParseUint(d.Attrs["queue/logical_block_size"], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	physical, err := strconv.ParseUint(d.Attrs["queue/physical_block_size"], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return int(logical), int(physical), true
+}
+
+// Path returns the physical path of the device (e.g.
+// `pci-0000:00:1d.0-usb-0:1.2:1.0`), as found in its `ID_PATH`. When
+// udev has not set it, the path is derived from the ancestors in the
+// Devpath, using a subset of the udev heuristics: the closest USB device
+// or interface becomes `usb-0:<port path>`, the closest PCI device
+// `pci-<slot>`, and devices under `platform` `platform-<name>`. Other
+// buses are not supported, so the result may not match udev, and an
+// empty string is returned when no bus is recognised.
+func (d *Device) Path() string {
+	if p := d.Env["ID_PATH"]; p != "" {
+		return p
+	}
+
+	var parts []string
+	var usb, pci bool
+	components := strings.Split(d.Devpath, "/")
+	for i := len(components) - 1; i >= 0; i-- {
+		name := components[i]
+
+		switch {
+		case !usb && usbPortPathPattern.MatchString(name):
+			_, port, _ := strings.Cut(name, "-")
+			parts = append(parts, "usb-0:"+port)
+			usb = true
+		case !pci && pciSlotPattern.MatchString(name):
+			parts = append(parts, "pci-"+name)
+			pci = true
+		case i == 1 && components[0] == "platform":
+			parts = append(parts, "platform-"+name)
+		}
+	}
+
+	slices.Reverse(parts)
+	return strings.Join(parts, "-")
+}
+
+// Devnode returns the absolute path of the device node (e.g.
+// `/dev/input/event2`), based on its `DEVNAME`. An empty string is
+// returned for devices without a node.
+func (d *Device) Devnode() string {
+	devname := d.Env["DEVNAME"]
+	if devname == "" || path.IsAbs(devname) {
+		return devname
+	}
+
+	return path.Join("/dev", devname)
+}
+
+// AbsoluteDevLinks returns the Links of the device as absolute paths
+// under devRoot (e.g. `/dev/input/by-id/usb-Logitech_USB_Optical_Mouse-mouse`),
+// so that they can be used directly. An empty devRoot defaults to `/dev`.
+func (d *Device) AbsoluteDevLinks(devRoot string) []string {
+	if devRoot == "" {
+		devRoot = "/dev"
+	}
+
+	links := make([]string, 0, len(d.Links))
+	for _, l := range d.Links {
+		links = append(links, path.Join(devRoot, l))
+	}
+
+	return links
+}
+
+// ID returns a stable identifier of the device, following the udev
+// scheme used to name its data files: `b8:0` and `c189:133` for block
+// and char devices, `n2` for network interfaces, and `+usb:2-1.2` for
+// other devices. Devices without a Subsystem nor a dev number fall back
+// to their Devpath.
+func (d *Device) ID() string {
+	if dev := d.Attrs["dev"]; dev != "" {
+		if _, _, ok := d.devNumbers(); ok {
+			if d.Subsystem == "block" {
+				return "b" + dev
+			}
+			return "c" + dev
+		}
+	}
+
+	if d.Subsystem == "" {
+		return d.Devpath
+	}
+
+	if ifindex := d.Env["IFINDEX"]; d.Subsystem == "net" && ifindex != "" {
+		return "n" + ifindex
+	}
+
+	return "+" + d.Subsystem + ":" + path.Base(d.Devpath)
+}
+
+// Seqnum returns the sequence number of the kernel uevent of the device,
+// parsed from its `SEQNUM`. The returned bool is false when it is
+// missing or malformed, as for devices scanned from sysfs without udev
+// data.
+func (d *Device) Seqnum() (uint64, bool) {
+	seqnum, err := strconv.ParseUint(d.Env["SEQNUM"], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seqnum, true
+}
+
+// Major returns the major number of the device, parsed from the `dev`
+// attr (e.g. `189` for `189:133`). The returned bool is false when the
+// attr is missing or malformed.
+func (d *Device) Major() (int, bool) {
+	major, _, ok := d.devNumbers()
+	return major, ok
+}
+
+// Minor returns the minor number of the device, parsed from the `dev`
+// attr (e.g. `133` for `189:133`). The returned bool is false when the
+// attr is missing or malformed.
+func (d *Device) Minor() (int, bool) {
+	_, minor, ok := d.devNumbers()
+	return minor, ok
+}
+
+func (d *Device) devNumbers() (int, int, bool) {
+	ma, mi, ok := strings.Cut(d.Attrs["dev"], ":")
+	if !ok {
+		return 0, 0, false
+	}
+
+	major, err := strconv.ParseUint(ma, 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	minor, err := strconv.ParseUint(mi, 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return int(major), int(minor), true
+}
+
+// USBMaxPowerMilliamps returns the maximum power drawn from the bus by a
+// USB device in its active configuration, in mA, parsed from its
+// `bMaxPower` attr (e.g. `500mA`). Self-powered devices may report 0.
+// The returned bool is false when the attr is missing or malformed.
+func (d *Device) USBMaxPowerMilliamps() (int, bool) {
+	v, ok := strings.CutSuffix(d.Attrs["bMaxPower"], "mA")
+	if !ok {
+		return 0, false
+	}
+
+	ma, err := strconv.ParseUint(strings.TrimSpace(v), 10, 16)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(ma), true
+}
+
+// USBEndpoint returns the polling interval (`bInterval`) and the maximum
+// packet size (`wMaxPacketSize`) of a USB endpoint, which sysfs exposes
+// as hex values. The returned bool is false for devices that are not
+// USB endpoints, or when the attrs are missing or malformed.
+func (d *Device) USBEndpoint() (int, int, bool) {
+	if d.Env["DEVTYPE"] != "usb_endpoint" && !usbEndpointPattern.MatchString(path.Base(d.Devpath)) {
+		return 0, 0, false
+	}
+
+	interval, err := strconv.ParseUint(d.Attrs["bInterval"], 16, 8)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	maxPacket, err := strconv.ParseUint(d.Attrs["wMaxPacketSize"], 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return int(interval), int(maxPacket), true
+}
@@ -0,0 +1,332 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetParentWithSubsystem(t *testing.T) {
+	usbDevice := &Device{Devpath: "usb2/2-1", Subsystem: "usb"}
+	usbInterface := &Device{Devpath: "usb2/2-1/2-1:1.0", Subsystem: "usb", Parent: usbDevice}
+	hid := &Device{Devpath: "usb2/2-1/2-1:1.0/0003:046D:C05B.0001", Subsystem: "hid", Parent: usbInterface}
+	input := &Device{Devpath: "usb2/2-1/2-1:1.0/0003:046D:C05B.0001/input/input2", Subsystem: "input", Parent: hid}
+	event := &Device{Devpath: "usb2/2-1/2-1:1.0/0003:046D:C05B.0001/input/input2/event2", Subsystem: "input", Parent: input}
+
+	if p := event.GetParentWithSubsystem("usb"); p != usbInterface {
+		t.Errorf("want %v got %v", usbInterface, p)
+	}
+
+	if p := event.GetParentWithSubsystem("input"); p != input {
+		t.Errorf("want %v got %v", input, p)
+	}
+
+	if p := event.GetParentWithSubsystem("pci"); p != nil {
+		t.Errorf("want nil got %v", p)
+	}
+
+	if p := usbDevice.GetParentWithSubsystem("usb"); p != nil {
+		t.Errorf("want nil got %v", p)
+	}
+
+	// cycles must not loop forever.
+	usbDevice.Parent = event
+	if p := event.GetParentWithSubsystem("pci"); p != nil {
+		t.Errorf("want nil got %v", p)
+	}
+}
+
+func TestUSBPortPath(t *testing.T) {
+	tests := []struct {
+		devpath string
+		want    string
+	}{
+		{devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2", want: "2-1.2"},
+		{devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0", want: "2-1.2"},
+		{devpath: "pci0000:00/0000:00:14.0/usb1/1-1/1-1.2/1-1.2.3", want: "1-1.2.3"},
+		{devpath: "pci0000:00/0000:00:1d.0/usb2/2-1", want: "2-1"},
+		{devpath: "pci0000:00/0000:00:1d.0/usb2", want: ""},
+		{devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2", want: ""},
+		{devpath: "platform/serial8250/tty/ttyS17", want: ""},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Devpath: tc.devpath}
+		if got := d.USBPortPath(); got != tc.want {
+			t.Errorf("%s: want %q got %q", tc.devpath, tc.want, got)
+		}
+	}
+}
+
+func TestDevnode(t *testing.T) {
+	tests := []struct {
+		devname string
+		want    string
+	}{
+		{devname: "input/event2", want: "/dev/input/event2"},
+		{devname: "ttyS17", want: "/dev/ttyS17"},
+		{devname: "/dev/sda", want: "/dev/sda"},
+		{devname: "", want: ""},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Env: map[string]string{"DEVNAME": tc.devname}}
+		if got := d.Devnode(); got != tc.want {
+			t.Errorf("%q: want %q got %q", tc.devname, tc.want, got)
+		}
+	}
+}
+
+func TestMajorMinor(t *testing.T) {
+	tests := []struct {
+		dev       string
+		wantMajor int
+		wantMinor int
+		wantOk    bool
+	}{
+		{dev: "189:133", wantMajor: 189, wantMinor: 133, wantOk: true},
+		{dev: "8:0", wantMajor: 8, wantMinor: 0, wantOk: true},
+		{dev: "", wantOk: false},
+		{dev: "189", wantOk: false},
+		{dev: "189:", wantOk: false},
+		{dev: "a:1", wantOk: false},
+		{dev: "-1:1", wantOk: false},
+		{dev: "1:2:3", wantOk: false},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Attrs: map[string]string{"dev": tc.dev}}
+
+		major, ok := d.Major()
+		if major != tc.wantMajor || ok != tc.wantOk {
+			t.Errorf("%q: want major %d, %v got %d, %v", tc.dev, tc.wantMajor, tc.wantOk, major, ok)
+		}
+
+		minor, ok := d.Minor()
+		if minor != tc.wantMinor || ok != tc.wantOk {
+			t.Errorf("%q: want minor %d, %v got %d, %v", tc.dev, tc.wantMinor, tc.wantOk, minor, ok)
+		}
+	}
+
+	d := &Device{}
+	if _, ok := d.Major(); ok {
+		t.Error("want no major for device without attrs")
+	}
+}
+
+func TestUSBEndpoint(t *testing.T) {
+	tests := []struct {
+		devpath   string
+		devtype   string
+		interval  string
+		maxPacket string
+		want      [2]int
+		wantOk    bool
+	}{
+		{devpath: "1-1:1.0/ep_81", devtype: "usb_endpoint", interval: "0a", maxPacket: "0008", want: [2]int{10, 8}, wantOk: true},
+		{devpath: "1-1/ep_00", interval: "00", maxPacket: "0040", want: [2]int{0, 64}, wantOk: true},
+		{devpath: "1-1:1.0", devtype: "usb_interface", interval: "0a", maxPacket: "0008"},
+		{devpath: "1-1:1.0/ep_81", devtype: "usb_endpoint", interval: "zz", maxPacket: "0008"},
+		{devpath: "1-1:1.0/ep_81", devtype: "usb_endpoint", interval: "0a"},
+	}
+
+	for _, tc := range tests {
+		d := &Device{
+			Devpath: tc.devpath,
+			Env:     map[string]string{"DEVTYPE": tc.devtype},
+			Attrs:   map[string]string{"bInterval": tc.interval, "wMaxPacketSize": tc.maxPacket},
+		}
+
+		interval, maxPacket, ok := d.USBEndpoint()
+		if [2]int{interval, maxPacket} != tc.want || ok != tc.wantOk {
+			t.Errorf("%s: want %v, %v got %d, %d, %v", tc.devpath, tc.want, tc.wantOk, interval, maxPacket, ok)
+		}
+	}
+}
+
+func TestUnescapeUdev(t *testing.T) {
+	tests := map[string]string{
+		`USB\x20Optical\x20Mouse`: "USB Optical Mouse",
+		`Logitech`:                "Logitech",
+		`a\x2fb\x5cc`:             `a/b\c`,
+		`trailing\x2`:             `trailing\x2`,
+		`trailing\x`:              `trailing\x`,
+		`trailing\`:               `trailing\`,
+		`bad\xzz\x20`:             `bad\xzz `,
+		``:                        ``,
+	}
+
+	for in, want := range tests {
+		if got := UnescapeUdev(in); got != want {
+			t.Errorf("%q: want %q got %q", in, want, got)
+		}
+	}
+}
+
+func TestDecodedEnv(t *testing.T) {
+	d := &Device{Env: map[string]string{"ID_MODEL_ENC": `USB\x20Optical\x20Mouse`}}
+
+	if got := d.DecodedEnv("ID_MODEL_ENC"); got != "USB Optical Mouse" {
+		t.Errorf("want %q got %q", "USB Optical Mouse", got)
+	}
+
+	if got := d.DecodedEnv("MISSING"); got != "" {
+		t.Errorf("want empty got %q", got)
+	}
+}
+
+func TestCanWakeup(t *testing.T) {
+	tests := []struct {
+		wakeup         string
+		enabled, found bool
+	}{
+		{wakeup: "enabled", enabled: true, found: true},
+		{wakeup: "disabled", enabled: false, found: true},
+		{wakeup: "", enabled: false, found: false},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Attrs: map[string]string{}}
+		if tc.wakeup != "" {
+			d.Attrs["power/wakeup"] = tc.wakeup
+		}
+
+		enabled, found := d.CanWakeup()
+		if enabled != tc.enabled || found != tc.found {
+			t.Errorf("%q: want (%v, %v) got (%v, %v)", tc.wakeup, tc.enabled, tc.found, enabled, found)
+		}
+	}
+}
+
+func TestAbsoluteDevLinks(t *testing.T) {
+	d := &Device{Links: []string{
+		"disk/by-id/usb-Generic_Flash_Disk_12345678-0:0",
+		"disk/by-path/pci-0000:00:14.0-usb-0:1:1.0-scsi-0:0:0:0",
+	}}
+
+	want := []string{
+		"/tmp/dev/disk/by-id/usb-Generic_Flash_Disk_12345678-0:0",
+		"/tmp/dev/disk/by-path/pci-0000:00:14.0-usb-0:1:1.0-scsi-0:0:0:0",
+	}
+	if got := d.AbsoluteDevLinks("/tmp/dev/"); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+
+	if got := d.AbsoluteDevLinks(""); got[0] != "/dev/disk/by-id/usb-Generic_Flash_Disk_12345678-0:0" {
+		t.Errorf("want link under /dev got %q", got[0])
+	}
+
+	if got := (&Device{}).AbsoluteDevLinks(""); len(got) != 0 {
+		t.Errorf("want no links got %v", got)
+	}
+}
+
+func TestUSBMaxPowerMilliamps(t *testing.T) {
+	tests := []struct {
+		maxPower string
+		want     int
+		ok       bool
+	}{
+		{maxPower: "500mA", want: 500, ok: true},
+		{maxPower: "0mA", want: 0, ok: true},
+		{maxPower: "98mA", want: 98, ok: true},
+		{maxPower: "500", ok: false},
+		{maxPower: "mA", ok: false},
+		{maxPower: "-2mA", ok: false},
+		{maxPower: "", ok: false},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Attrs: map[string]string{"bMaxPower": tc.maxPower}}
+
+		got, ok := d.USBMaxPowerMilliamps()
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%q: want (%d, %v) got (%d, %v)", tc.maxPower, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func TestBlockSizes(t *testing.T) {
+	tests := []struct {
+		attrs             map[string]string
+		logical, physical int
+		ok                bool
+	}{
+		{
+			attrs:   map[string]string{"queue/logical_block_size": "512", "queue/physical_block_size": "4096"},
+			logical: 512, physical: 4096, ok: true,
+		},
+		{attrs: map[string]string{"queue/logical_block_size": "512"}},
+		{attrs: map[string]string{"queue/logical_block_size": "x", "queue/physical_block_size": "4096"}},
+		{attrs: map[string]string{}},
+	}
+
+	for _, tc := range tests {
+		d := &Device{Attrs: tc.attrs}
+
+		logical, physical, ok := d.BlockSizes()
+		if logical != tc.logical || physical != tc.physical || ok != tc.ok {
+			t.Errorf("%v: want (%d, %d, %v) got (%d, %d, %v)", tc.attrs, tc.logical, tc.physical, tc.ok, logical, physical, ok)
+		}
+	}
+}
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		device *Device
+		want   string
+	}{
+		{
+			device: &Device{Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.4", Subsystem: "usb", Attrs: map[string]string{"dev": "189:133"}},
+			want:   "c189:133",
+		},
+		{
+			device: &Device{Devpath: "virtual/block/loop0", Subsystem: "block", Attrs: map[string]string{"dev": "7:0"}},
+			want:   "b7:0",
+		},
+		{
+			device: &Device{Devpath: "virtual/net/lo", Subsystem: "net", Env: map[string]string{"IFINDEX": "1"}},
+			want:   "n1",
+		},
+		{
+			device: &Device{Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0", Subsystem: "usb"},
+			want:   "+usb:2-1.2:1.0",
+		},
+		{
+			device: &Device{Devpath: "platform/serial8250"},
+			want:   "platform/serial8250",
+		},
+		{
+			device: &Device{Devpath: "platform/serial8250/tty/ttyS0", Attrs: map[string]string{"dev": "4:64"}},
+			want:   "c4:64",
+		},
+	}
+
+	for _, tc := range tests {
+		if got := tc.device.ID(); got != tc.want {
+			t.Errorf("%s: want %q got %q", tc.device.Devpath, tc.want, got)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	tests := map[string]string{
+		"pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2": "pci-0000:00:1d.0-usb-0:1.2:1.0",
+		"pci0000:00/0000:00:1d.0/usb2/2-1":                "pci-0000:00:1d.0-usb-0:1",
+		"pci0000:00/0000:00:1a.0/usb1":                    "pci-0000:00:1a.0",
+		"pci0000:00/0000:00:1c.0/0000:03:00.0/nvme/nvme0": "pci-0000:03:00.0",
+		"platform/serial8250/tty/ttyS17":                  "platform-serial8250",
+		"virtual/net/lo":                                  "",
+	}
+
+	for devpath, want := range tests {
+		d := &Device{Devpath: devpath}
+		if got := d.Path(); got != want {
+			t.Errorf("%s: want %q got %q", devpath, want, got)
+		}
+	}
+
+	d := &Device{Devpath: "virtual/net/lo", Env: map[string]string{"ID_PATH": "custom"}}
+	if got := d.Path(); got != "custom" {
+		t.Errorf("want ID_PATH %q got %q", "custom", got)
+	}
+}
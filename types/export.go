@@ -0,0 +1,44 @@
+package types
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Export renders the device in the format of `udevadm info`, as the
+// following lines:
+//
+//	P: /devices/pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2
+//	N: input/event2
+//	S: input/by-id/usb-Logitech_USB_Optical_Mouse-event-mouse
+//	G: seat
+//	E: DEVNAME=/dev/input/event2
+//
+// The N line is omitted for devices without a node. Tags and the Env
+// keys are sorted.
+func (d *Device) Export() string {
+	var b strings.Builder
+
+	b.WriteString("P: /devices/" + d.Devpath + "\n")
+
+	if devname := d.Env["DEVNAME"]; devname != "" {
+		b.WriteString("N: " + strings.TrimPrefix(devname, "/dev/") + "\n")
+	}
+
+	for _, l := range d.Links {
+		b.WriteString("S: " + l + "\n")
+	}
+
+	tags := slices.Clone(d.Tags)
+	slices.Sort(tags)
+	for _, t := range tags {
+		b.WriteString("G: " + t + "\n")
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(d.Env)) {
+		b.WriteString("E: " + k + "=" + d.Env[k] + "\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	d := &Device{
+		Devpath: "pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2",
+		Env: map[string]string{
+			"MAJOR":     "13",
+			"MINOR":     "66",
+			"DEVNAME":   "input/event2",
+			"ID_SERIAL": "Logitech_USB_Optical_Mouse",
+		},
+		Links: []string{
+			"input/by-id/usb-Logitech_USB_Optical_Mouse-event-mouse",
+			"input/by-path/pci-0000:00:1d.0-usb-0:1.2:1.0-event-mouse",
+		},
+		Tags: []string{"systemd", "seat"},
+	}
+
+	want := `P: /devices/pci0000:00/0000:00:1d.0/usb2/2-1/2-1.2/2-1.2:1.0/0003:046D:C05B.0001/input/input2/event2
+N: input/event2
+S: input/by-id/usb-Logitech_USB_Optical_Mouse-event-mouse
+S: input/by-path/pci-0000:00:1d.0-usb-0:1.2:1.0-event-mouse
+G: seat
+G: systemd
+E: DEVNAME=input/event2
+E: ID_SERIAL=Logitech_USB_Optical_Mouse
+E: MAJOR=13
+E: MINOR=66
+`
+	if got := d.Export(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	if d.Tags[0] != "systemd" {
+		t.Error("tags of the device sorted in place")
+	}
+
+	d = &Device{Devpath: "platform/serial8250"}
+	if got := d.Export(); got != "P: /devices/platform/serial8250\n" {
+		t.Errorf("want only the P line got %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// deviceProperties holds the properties of a single device, without any
+// of its tree links.
+type deviceProperties struct {
+	Devpath         string
+	Sysname         string            `json:",omitempty"`
+	Sysnum          string            `json:",omitempty"`
+	Subsystem       string            `json:",omitempty"`
+	Driver          string            `json:",omitempty"`
+	Env             map[string]string `json:",omitempty"`
+	Attrs           map[string]string `json:",omitempty"`
+	Tags            []string          `json:",omitempty"`
+	Links           []string          `json:",omitempty"`
+	UsecInitialized string            `json:",omitempty"`
+	VendorID        string            `json:",omitempty"`
+	ProductID       string            `json:",omitempty"`
+	Synthetic       bool              `json:",omitempty"`
+}
+
+// MarshalDeviceJSON returns the JSON encoding of the device properties,
+// without its Parent and Children, which is handy for logging individual
+// devices or events. Env and Attrs keys and Tags are sorted.
+func (d *Device) MarshalDeviceJSON() ([]byte, error) {
+	return json.Marshal(d.properties())
+}
+
+func (d *Device) properties() deviceProperties {
+	tags := slices.Clone(d.Tags)
+	slices.Sort(tags)
+
+	return deviceProperties{
+		Devpath:         d.Devpath,
+		Sysname:         d.Sysname,
+		Sysnum:          d.Sysnum,
+		Subsystem:       d.Subsystem,
+		Driver:          d.Driver,
+		Env:             d.Env,
+		Attrs:           d.Attrs,
+		Tags:            tags,
+		Links:           d.Links,
+		UsecInitialized: d.UsecInitialized,
+		VendorID:        d.VendorID,
+		ProductID:       d.ProductID,
+		Synthetic:       d.Synthetic,
+	}
+}
+
+// deviceTree is the JSON representation of a device and its descendants,
+// where the parent is referenced by its Devpath to avoid cycles.
+type deviceTree struct {
+	deviceProperties
+	ParentDevpath string    `json:",omitempty"`
+	Children      []*Device `json:",omitempty"`
+}
+
+// MarshalJSON returns the JSON encoding of the device and, recursively,
+// of its Children. The Parent is encoded as its Devpath, under the
+// `ParentDevpath` key.
+func (d *Device) MarshalJSON() ([]byte, error) {
+	t := deviceTree{
+		deviceProperties: d.properties(),
+		Children:         d.Children,
+	}
+	if d.Parent != nil {
+		t.ParentDevpath = d.Parent.Devpath
+	}
+
+	return json.Marshal(t)
+}
+
+// UnmarshalJSON decodes a device encoded with MarshalJSON, linking its
+// Children back to it. The Parent of the top-level device is left nil,
+// as only its Devpath is available.
+func (d *Device) UnmarshalJSON(data []byte) error {
+	var t deviceTree
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+
+	p := t.deviceProperties
+	*d = Device{
+		Devpath:         p.Devpath,
+		Sysname:         p.Sysname,
+		Sysnum:          p.Sysnum,
+		Subsystem:       p.Subsystem,
+		Driver:          p.Driver,
+		Env:             p.Env,
+		Attrs:           p.Attrs,
+		Tags:            p.Tags,
+		Links:           p.Links,
+		UsecInitialized: p.UsecInitialized,
+		VendorID:        p.VendorID,
+		ProductID:       p.ProductID,
+		Synthetic:       p.Synthetic,
+		Children:        t.Children,
+	}
+
+	for _, c := range d.Children {
+		c.Parent = d
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalDeviceJSON(t *testing.T) {
+	parent := &Device{Devpath: "usb2/2-1"}
+	d := &Device{
+		Devpath:   "usb2/2-1/2-1.2",
+		Subsystem: "usb",
+		Driver:    "usb",
+		Env:       map[string]string{"DEVTYPE": "usb_device", "DEVNAME": "bus/usb/002/004"},
+		Attrs:     map[string]string{"idVendor": "046d", "idProduct": "c05b"},
+		Tags:      []string{"uaccess", "seat"},
+		VendorID:  "046d",
+		ProductID: "c05b",
+		Parent:    parent,
+	}
+	d.Children = []*Device{{Devpath: "usb2/2-1/2-1.2/2-1.2:1.0", Parent: d}}
+	parent.Children = []*Device{d}
+
+	data, err := d.MarshalDeviceJSON()
+	if err != nil {
+		t.Fatal("failed to marshal device", err)
+	}
+
+	want := `{"Devpath":"usb2/2-1/2-1.2","Subsystem":"usb","Driver":"usb",` +
+		`"Env":{"DEVNAME":"bus/usb/002/004","DEVTYPE":"usb_device"},` +
+		`"Attrs":{"idProduct":"c05b","idVendor":"046d"},` +
+		`"Tags":["seat","uaccess"],"VendorID":"046d","ProductID":"c05b"}`
+	if string(data) != want {
+		t.Errorf("want %s got %s", want, data)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"Parent", "Children"} {
+		if _, ok := fields[k]; ok {
+			t.Errorf("want %s omitted", k)
+		}
+	}
+
+	if d.Tags[0] != "uaccess" {
+		t.Error("want device tags left unsorted")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	root := &Device{Devpath: "usb2", Env: map[string]string{"DEVNAME": "bus/usb/002/001"}}
+	hub := &Device{Devpath: "usb2/2-1", Parent: root, VendorID: "8087"}
+	mouse := &Device{Devpath: "usb2/2-1/2-1.2", Parent: hub, Tags: []string{"seat"}}
+	printer := &Device{Devpath: "usb2/2-1/2-1.4", Parent: hub}
+	root.Children = []*Device{hub}
+	hub.Children = []*Device{mouse, printer}
+
+	data, err := json.Marshal(hub)
+	if err != nil {
+		t.Fatal("failed to marshal device", err)
+	}
+
+	want := `{"Devpath":"usb2/2-1","VendorID":"8087","ParentDevpath":"usb2","Children":[` +
+		`{"Devpath":"usb2/2-1/2-1.2","Tags":["seat"],"ParentDevpath":"usb2/2-1"},` +
+		`{"Devpath":"usb2/2-1/2-1.4","ParentDevpath":"usb2/2-1"}]}`
+	if string(data) != want {
+		t.Errorf("want %s got %s", want, data)
+	}
+
+	data, err = json.Marshal([]*Device{root})
+	if err != nil {
+		t.Fatal("failed to marshal devices", err)
+	}
+
+	var got []*Device
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal("failed to unmarshal devices", err)
+	}
+
+	if len(got) != 1 || got[0].Parent != nil || got[0].Env["DEVNAME"] != "bus/usb/002/001" {
+		t.Fatalf("want root device got %+v", got)
+	}
+
+	gotHub := got[0].Children[0]
+	if gotHub.Parent != got[0] || gotHub.VendorID != "8087" {
+		t.Errorf("want hub linked to root got %+v", gotHub)
+	}
+
+	if len(gotHub.Children) != 2 {
+		t.Fatalf("wanted 2 children got %d", len(gotHub.Children))
+	}
+
+	for _, c := range gotHub.Children {
+		if c.Parent != gotHub {
+			t.Errorf("want %q linked to hub", c.Devpath)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Modalias returns the modalias of the device (e.g.
+// `usb:v046DpC05Bd5400dc00dsc00dp00ic03isc01ip02in00`), taken from its
+// `MODALIAS` or, failing that, from its `modalias` attr.
+func (d *Device) Modalias() string {
+	if m := d.Env["MODALIAS"]; m != "" {
+		return m
+	}
+
+	return d.Attrs["modalias"]
+}
+
+// ModaliasModules returns the modules that would be autoloaded for the
+// device, by matching its Modalias against aliasDB, a database in the
+// `modules.alias` format:
+//
+//	alias usb:v*p*d*dc*dsc*dp*ic03isc01ip02in* usbhid
+//
+// Alias patterns may contain the `*`, `?` and `[...]` wildcards, which are
+// matched as done by kmod (fnmatch without FNM_PATHNAME), so that `*`
+// also matches `/`. Modules are returned once each, in the order found in
+// aliasDB; devices without a modalias have no modules.
+func (d *Device) ModaliasModules(aliasDB io.Reader) ([]string, error) {
+	modalias := d.Modalias()
+	if modalias == "" {
+		return nil, nil
+	}
+
+	var modules []string
+	scanner := bufio.NewScanner(aliasDB)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 || fields[0] != "alias" {
+			return nil, fmt.Errorf("malformed alias at line %d", n)
+		}
+
+		ok, err := matchAlias(fields[1], modalias)
+		if err != nil {
+			return nil, fmt.Errorf("malformed alias at line %d: %w", n, err)
+		}
+		if ok && !slices.Contains(modules, fields[2]) {
+			modules = append(modules, fields[2])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+// matchAlias reports whether s matches the fnmatch pattern, where `*`
+// matches any bytes and `?` any single byte, both including `/`, and `\`
+// escapes the next byte. The returned error wraps path.ErrBadPattern for
+// unterminated classes.
+func matchAlias(pattern, s string) (bool, error) {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '[':
+			_, n, err := matchClass(pattern[i:], 0)
+			if err != nil {
+				return false, err
+			}
+			i += n - 1
+		}
+	}
+
+	// Backtrack to the last `*` on mismatch, letting it match one more
+	// byte of s.
+	p, i := 0, 0
+	star, next := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch c := pattern[p]; {
+			case c == '*':
+				star, next = p, i
+				p++
+				continue
+			case c == '?':
+				p++
+				i++
+				continue
+			case c == '[':
+				ok, n, _ := matchClass(pattern[p:], s[i])
+				if ok {
+					p += n
+					i++
+					continue
+				}
+			case c == '\\' && p+1 < len(pattern):
+				if pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			case c == s[i]:
+				p++
+				i++
+				continue
+			}
+		}
+
+		if star < 0 {
+			return false, nil
+		}
+		next++
+		p, i = star+1, next
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern), nil
+}
+
+// matchClass matches c against the `[...]` class at the start of
+// pattern, which may be negated with `!` or `^` and contain ranges (e.g.
+// `[0-9a-f]`). It returns the width of the class in pattern.
+func matchClass(pattern string, c byte) (bool, int, error) {
+	i := 1
+	negate := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negate {
+		i++
+	}
+
+	matched := false
+	for first := true; ; first = false {
+		if i >= len(pattern) {
+			return false, 0, fmt.Errorf("%w: unterminated class", path.ErrBadPattern)
+		}
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, nil
+		}
+
+		lo := pattern[i]
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+}
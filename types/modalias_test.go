@@ -0,0 +1,130 @@
+package types
+
+import (
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const aliasDB = `# Aliases extracted from modules themselves.
+alias usb:v*p*d*dc*dsc*dp*ic03isc01ip02in* usbhid
+alias usb:v046DpC05B*dc*dsc*dp*ic*isc*ip*in* hid_logitech
+alias usb:v*p*d*dc*dsc*dp*ic03isc*ip*in* usbhid
+alias usb:v*p*d*dc*dsc*dp*ic07isc01ip0[123]in* usblp
+alias pci:v00008086d*sv*sd*bc0Csc03i20* ehci_pci
+alias dmi:*:pnK55A*:* asus_nb_wmi
+alias of:N*T*Cgpio-keys[!0-9]? gpio_keys
+`
+
+func TestModaliasModules(t *testing.T) {
+	tests := []struct {
+		name   string
+		device *Device
+		want   []string
+	}{
+		{
+			name: "mouse",
+			device: &Device{Env: map[string]string{
+				"MODALIAS": "usb:v046DpC05Bd5400dc00dsc00dp00ic03isc01ip02in00",
+			}},
+			want: []string{"usbhid", "hid_logitech"},
+		},
+		{
+			name: "printer from attr",
+			device: &Device{Attrs: map[string]string{
+				"modalias": "usb:v03F0p1C17d0100dc00dsc00dp00ic07isc01ip02in00",
+			}},
+			want: []string{"usblp"},
+		},
+		{
+			name: "star spanning a slash",
+			device: &Device{Env: map[string]string{
+				"MODALIAS": "dmi:bvnAmericanMegatrendsInc.:pnK55A/K55B:rvnASUSTeK:",
+			}},
+			want: []string{"asus_nb_wmi"},
+		},
+		{
+			name: "negated class and any byte",
+			device: &Device{Env: map[string]string{
+				"MODALIAS": "of:Ngpio-keysT(null)Cgpio-keys/x",
+			}},
+			want: []string{"gpio_keys"},
+		},
+		{
+			name: "no match",
+			device: &Device{Env: map[string]string{
+				"MODALIAS": "platform:serial8250",
+			}},
+		},
+		{
+			name:   "no modalias",
+			device: &Device{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.device.ModaliasModules(strings.NewReader(aliasDB))
+			if err != nil {
+				t.Fatal("failed to match aliases", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestModaliasModulesMalformed(t *testing.T) {
+	d := &Device{Env: map[string]string{"MODALIAS": "usb:v046DpC05B"}}
+
+	for _, db := range []string{"alias usb:v*\n", "alias usb:v[ usbhid\n", "softdep usbhid pre: hid\n"} {
+		if _, err := d.ModaliasModules(strings.NewReader(db)); err == nil {
+			t.Errorf("want error for %q", db)
+		}
+	}
+}
+
+func TestMatchAlias(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "usb:v*p*", s: "usb:v046DpC05B", want: true},
+		{pattern: "a*b", s: "a/x/b", want: true},
+		{pattern: "a?b", s: "a/b", want: true},
+		{pattern: "a*b", s: "a/x/c"},
+		{pattern: "ip0[123]in*", s: "ip02in00", want: true},
+		{pattern: "ip0[!123]in*", s: "ip02in00"},
+		{pattern: "ip0[^4-9]in*", s: "ip02in00", want: true},
+		{pattern: "[]x]", s: "]", want: true},
+		{pattern: `a\*`, s: "a*", want: true},
+		{pattern: `a\*`, s: "ab"},
+		{pattern: "**", s: "", want: true},
+		{pattern: "a", s: "ab"},
+	}
+
+	for _, tc := range tests {
+		got, err := matchAlias(tc.pattern, tc.s)
+		if err != nil {
+			t.Fatalf("failed to match %q: %v", tc.pattern, err)
+		}
+		if got != tc.want {
+			t.Errorf("matchAlias(%q, %q): want %v got %v", tc.pattern, tc.s, tc.want, got)
+		}
+	}
+
+	for _, pattern := range []string{"a[", "a[b-", "[]"} {
+		if _, err := matchAlias(pattern, "a"); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("want ErrBadPattern for %q got %v", pattern, err)
+		}
+	}
+
+	if ok, err := matchAlias(`a\[`, "a["); err != nil || !ok {
+		t.Errorf("want escaped class to match literally got %v, %v", ok, err)
+	}
+}
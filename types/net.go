@@ -0,0 +1,65 @@
+package types
+
+import (
+	"net"
+	"strconv"
+)
+
+// isNet reports whether the device is a network interface, based on its
+// Subsystem or, for trees without subsystem links, its `INTERFACE`.
+func (d *Device) isNet() bool {
+	return d.Subsystem == "net" || d.Env["INTERFACE"] != ""
+}
+
+// InterfaceName returns the name of a network interface (e.g. `eth0`),
+// or an empty string for other devices.
+func (d *Device) InterfaceName() string {
+	if !d.isNet() {
+		return ""
+	}
+
+	if name := d.Env["INTERFACE"]; name != "" {
+		return name
+	}
+
+	return d.Sysname
+}
+
+// HardwareAddr returns the MAC address of a network interface, parsed
+// from its `address` attr. Other devices, and interfaces without an
+// address, return a nil address and no error.
+func (d *Device) HardwareAddr() (net.HardwareAddr, error) {
+	addr := d.Attrs["address"]
+	if !d.isNet() || addr == "" {
+		return nil, nil
+	}
+
+	return net.ParseMAC(addr)
+}
+
+// IfIndex returns the index of a network interface, parsed from its
+// `ifindex` attr. The returned bool is false for other devices, or when
+// the attr is missing or malformed.
+func (d *Device) IfIndex() (int, bool) {
+	if !d.isNet() {
+		return 0, false
+	}
+
+	i, err := strconv.ParseUint(d.Attrs["ifindex"], 10, 31)
+	if err != nil {
+		return 0, false
+	}
+
+	return int(i), true
+}
+
+// OperState returns the operational state of a network interface (e.g.
+// `up`, `down` or `unknown`), from its `operstate` attr. An empty string
+// is returned for other devices.
+func (d *Device) OperState() string {
+	if !d.isNet() {
+		return ""
+	}
+
+	return d.Attrs["operstate"]
+}
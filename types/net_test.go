@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestNetHelpers(t *testing.T) {
+	eth0 := &Device{
+		Devpath:   "pci0000:00/0000:00:04.0/virtio3/net/eth0",
+		Sysname:   "eth0",
+		Subsystem: "net",
+		Env:       map[string]string{"INTERFACE": "eth0", "IFINDEX": "2"},
+		Attrs: map[string]string{
+			"address":   "52:54:00:12:34:56",
+			"ifindex":   "2",
+			"operstate": "up",
+		},
+	}
+
+	if got := eth0.InterfaceName(); got != "eth0" {
+		t.Errorf("want interface %q got %q", "eth0", got)
+	}
+
+	addr, err := eth0.HardwareAddr()
+	if err != nil {
+		t.Fatal("failed to parse address", err)
+	}
+	if addr.String() != "52:54:00:12:34:56" {
+		t.Errorf("want address %q got %q", "52:54:00:12:34:56", addr)
+	}
+
+	if i, ok := eth0.IfIndex(); !ok || i != 2 {
+		t.Errorf("want ifindex 2 got (%d, %v)", i, ok)
+	}
+
+	if got := eth0.OperState(); got != "up" {
+		t.Errorf("want operstate %q got %q", "up", got)
+	}
+
+	// Interfaces without the INTERFACE key use their sysname.
+	lo := &Device{Sysname: "lo", Subsystem: "net", Attrs: map[string]string{"address": "bogus"}}
+	if got := lo.InterfaceName(); got != "lo" {
+		t.Errorf("want interface %q got %q", "lo", got)
+	}
+	if _, err := lo.HardwareAddr(); err == nil {
+		t.Error("want error for malformed address")
+	}
+
+	// Other devices, even with a matching attr name, return zero values.
+	tty := &Device{Sysname: "ttyS0", Subsystem: "tty", Attrs: map[string]string{"address": "52:54:00:12:34:56", "operstate": "up"}}
+	if got := tty.InterfaceName(); got != "" {
+		t.Errorf("want no interface got %q", got)
+	}
+	if addr, err := tty.HardwareAddr(); addr != nil || err != nil {
+		t.Errorf("want no address got (%v, %v)", addr, err)
+	}
+	if _, ok := tty.IfIndex(); ok {
+		t.Error("want no ifindex")
+	}
+	if got := tty.OperState(); got != "" {
+		t.Errorf("want no operstate got %q", got)
+	}
+}
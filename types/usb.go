@@ -0,0 +1,182 @@
+package types
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// USB descriptor types, as found in bDescriptorType.
+const (
+	USBDescriptorDevice        = 0x01
+	USBDescriptorConfiguration = 0x02
+	USBDescriptorInterface     = 0x04
+	USBDescriptorEndpoint      = 0x05
+)
+
+// ErrInvalidDescriptor is returned when a USB descriptors blob is
+// truncated or malformed.
+var ErrInvalidDescriptor = errors.New("invalid USB descriptor")
+
+// USBDescriptor is a single descriptor of the chain found in the
+// `descriptors` file of USB devices.
+type USBDescriptor struct {
+	// Length is the descriptor size in bytes (bLength).
+	Length uint8
+	// Type is the descriptor type (bDescriptorType), such as
+	// USBDescriptorDevice.
+	Type uint8
+	// Raw holds the whole descriptor, including its header.
+	Raw []byte
+}
+
+// USBDeviceDescriptor holds the fields of a device descriptor.
+type USBDeviceDescriptor struct {
+	USBVersion        uint16
+	Class             uint8
+	SubClass          uint8
+	Protocol          uint8
+	MaxPacketSize0    uint8
+	VendorID          uint16
+	ProductID         uint16
+	DeviceVersion     uint16
+	ManufacturerIndex uint8
+	ProductIndex      uint8
+	SerialIndex       uint8
+	NumConfigurations uint8
+}
+
+// USBConfigurationDescriptor holds the fields of a configuration
+// descriptor. MaxPower is in the units of the descriptor (2mA or 8mA,
+// depending on the USB version).
+type USBConfigurationDescriptor struct {
+	TotalLength        uint16
+	NumInterfaces      uint8
+	ConfigurationValue uint8
+	ConfigurationIndex uint8
+	Attributes         uint8
+	MaxPower           uint8
+}
+
+// USBInterfaceDescriptor holds the fields of an interface descriptor.
+type USBInterfaceDescriptor struct {
+	InterfaceNumber  uint8
+	AlternateSetting uint8
+	NumEndpoints     uint8
+	Class            uint8
+	SubClass         uint8
+	Protocol         uint8
+	InterfaceIndex   uint8
+}
+
+// USBEndpointDescriptor holds the fields of an endpoint descriptor.
+type USBEndpointDescriptor struct {
+	EndpointAddress uint8
+	Attributes      uint8
+	MaxPacketSize   uint16
+	Interval        uint8
+}
+
+// ParseUSBDescriptors decodes the chain of descriptors of a USB
+// `descriptors` file. Each descriptor starts with its bLength and
+// bDescriptorType; descriptors of unknown types are kept, and can be
+// decoded from their Raw bytes.
+func ParseUSBDescriptors(b []byte) ([]USBDescriptor, error) {
+	var descs []USBDescriptor
+	for off := 0; off < len(b); {
+		if len(b)-off < 2 {
+			return nil, fmt.Errorf("%w: truncated header at offset %d", ErrInvalidDescriptor, off)
+		}
+
+		length := int(b[off])
+		if length < 2 || off+length > len(b) {
+			return nil, fmt.Errorf("%w: bad length %d at offset %d", ErrInvalidDescriptor, length, off)
+		}
+
+		descs = append(descs, USBDescriptor{
+			Length: b[off],
+			Type:   b[off+1],
+			Raw:    b[off : off+length : off+length],
+		})
+		off += length
+	}
+
+	return descs, nil
+}
+
+// Device decodes a device descriptor. The returned bool is false when
+// the descriptor is of a different type or too short.
+func (d USBDescriptor) Device() (USBDeviceDescriptor, bool) {
+	if d.Type != USBDescriptorDevice || len(d.Raw) < 18 {
+		return USBDeviceDescriptor{}, false
+	}
+
+	r := d.Raw
+	return USBDeviceDescriptor{
+		USBVersion:        binary.LittleEndian.Uint16(r[2:]),
+		Class:             r[4],
+		SubClass:          r[5],
+		Protocol:          r[6],
+		MaxPacketSize0:    r[7],
+		VendorID:          binary.LittleEndian.Uint16(r[8:]),
+		ProductID:         binary.LittleEndian.Uint16(r[10:]),
+		DeviceVersion:     binary.LittleEndian.Uint16(r[12:]),
+		ManufacturerIndex: r[14],
+		ProductIndex:      r[15],
+		SerialIndex:       r[16],
+		NumConfigurations: r[17],
+	}, true
+}
+
+// Configuration decodes a configuration descriptor. The returned bool is
+// false when the descriptor is of a different type or too short.
+func (d USBDescriptor) Configuration() (USBConfigurationDescriptor, bool) {
+	if d.Type != USBDescriptorConfiguration || len(d.Raw) < 9 {
+		return USBConfigurationDescriptor{}, false
+	}
+
+	r := d.Raw
+	return USBConfigurationDescriptor{
+		TotalLength:        binary.LittleEndian.Uint16(r[2:]),
+		NumInterfaces:      r[4],
+		ConfigurationValue: r[5],
+		ConfigurationIndex: r[6],
+		Attributes:         r[7],
+		MaxPower:           r[8],
+	}, true
+}
+
+// Interface decodes an interface descriptor. The returned bool is false
+// when the descriptor is of a different type or too short.
+func (d USBDescriptor) Interface() (USBInterfaceDescriptor, bool) {
+	if d.Type != USBDescriptorInterface || len(d.Raw) < 9 {
+		return USBInterfaceDescriptor{}, false
+	}
+
+	r := d.Raw
+	return USBInterfaceDescriptor{
+		InterfaceNumber:  r[2],
+		AlternateSetting: r[3],
+		NumEndpoints:     r[4],
+		Class:            r[5],
+		SubClass:         r[6],
+		Protocol:         r[7],
+		InterfaceIndex:   r[8],
+	}, true
+}
+
+// Endpoint decodes an endpoint descriptor. The returned bool is false
+// when the descriptor is of a different type or too short.
+func (d USBDescriptor) Endpoint() (USBEndpointDescriptor, bool) {
+	if d.Type != USBDescriptorEndpoint || len(d.Raw) < 7 {
+		return USBEndpointDescriptor{}, false
+	}
+
+	r := d.Raw
+	return USBEndpointDescriptor{
+		EndpointAddress: r[2],
+		Attributes:      r[3],
+		MaxPacketSize:   binary.LittleEndian.Uint16(r[4:]),
+		Interval:        r[6],
+	}, true
+}
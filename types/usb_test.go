@@ -0,0 +1,96 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+// mouseDescriptors is the descriptors file of a USB optical mouse: its
+// device, configuration, interface, HID and endpoint descriptors.
+var mouseDescriptors = []byte{
+	0x12, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00, 0x08, 0x6d, 0x04, 0x5b, 0xc0, 0x00, 0x54, 0x01, 0x02, 0x00, 0x01,
+	0x09, 0x02, 0x22, 0x00, 0x01, 0x01, 0x00, 0xa0, 0x31,
+	0x09, 0x04, 0x00, 0x00, 0x01, 0x03, 0x01, 0x02, 0x00,
+	0x09, 0x21, 0x11, 0x01, 0x00, 0x01, 0x22, 0x34, 0x00,
+	0x07, 0x05, 0x81, 0x03, 0x04, 0x00, 0x0a,
+}
+
+func TestParseUSBDescriptors(t *testing.T) {
+	descs, err := ParseUSBDescriptors(mouseDescriptors)
+	if err != nil {
+		t.Fatal("failed to parse descriptors", err)
+	}
+
+	wantTypes := []uint8{USBDescriptorDevice, USBDescriptorConfiguration, USBDescriptorInterface, 0x21, USBDescriptorEndpoint}
+	if len(descs) != len(wantTypes) {
+		t.Fatalf("wanted %d descriptors got %d", len(wantTypes), len(descs))
+	}
+	for i, d := range descs {
+		if d.Type != wantTypes[i] {
+			t.Errorf("descriptor %d: want type %#x got %#x", i, wantTypes[i], d.Type)
+		}
+		if int(d.Length) != len(d.Raw) {
+			t.Errorf("descriptor %d: length %d does not match raw %d", i, d.Length, len(d.Raw))
+		}
+	}
+
+	dev, ok := descs[0].Device()
+	if !ok {
+		t.Fatal("failed to decode device descriptor")
+	}
+	wantDev := USBDeviceDescriptor{
+		USBVersion: 0x0200, MaxPacketSize0: 8, VendorID: 0x046d, ProductID: 0xc05b,
+		DeviceVersion: 0x5400, ManufacturerIndex: 1, ProductIndex: 2, NumConfigurations: 1,
+	}
+	if dev != wantDev {
+		t.Errorf("want %+v got %+v", wantDev, dev)
+	}
+
+	cfg, ok := descs[1].Configuration()
+	if !ok {
+		t.Fatal("failed to decode configuration descriptor")
+	}
+	wantCfg := USBConfigurationDescriptor{TotalLength: 34, NumInterfaces: 1, ConfigurationValue: 1, Attributes: 0xa0, MaxPower: 0x31}
+	if cfg != wantCfg {
+		t.Errorf("want %+v got %+v", wantCfg, cfg)
+	}
+
+	intf, ok := descs[2].Interface()
+	if !ok {
+		t.Fatal("failed to decode interface descriptor")
+	}
+	wantIntf := USBInterfaceDescriptor{NumEndpoints: 1, Class: 3, SubClass: 1, Protocol: 2}
+	if intf != wantIntf {
+		t.Errorf("want %+v got %+v", wantIntf, intf)
+	}
+
+	ep, ok := descs[4].Endpoint()
+	if !ok {
+		t.Fatal("failed to decode endpoint descriptor")
+	}
+	wantEp := USBEndpointDescriptor{EndpointAddress: 0x81, Attributes: 3, MaxPacketSize: 4, Interval: 10}
+	if ep != wantEp {
+		t.Errorf("want %+v got %+v", wantEp, ep)
+	}
+
+	if _, ok := descs[0].Interface(); ok {
+		t.Error("device descriptor decoded as interface")
+	}
+}
+
+func TestParseUSBDescriptorsInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated header": {0x09},
+		"zero length":      {0x00, 0x01},
+		"overflow":         {0x09, 0x02, 0x22},
+	}
+
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseUSBDescriptors(b)
+			if !errors.Is(err, ErrInvalidDescriptor) {
+				t.Errorf("want ErrInvalidDescriptor got %v", err)
+			}
+		})
+	}
+}